@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/joshjon/sydneyweather/internal/weather"
+)
+
+type AirQualityResponse struct {
+	XMLName    xml.Name            `json:"-" xml:"air_quality"`
+	Provenance *ProvenanceResponse `json:"provenance" xml:"provenance"`
+	Time       time.Time           `json:"time" xml:"time"`
+	AQI        *int                `json:"aqi" xml:"aqi"`
+	Category   string              `json:"category" xml:"category"`
+	PM25       *float64            `json:"pm2_5" xml:"pm2_5"`
+	PM10       *float64            `json:"pm10" xml:"pm10"`
+	O3         *float64            `json:"o3" xml:"o3"`
+	NO2        *float64            `json:"no2" xml:"no2"`
+}
+
+func (r *AirQualityResponse) csvRecords() [][]string {
+	header, row := r.Provenance.csvFields()
+	return [][]string{
+		append(header, "time", "aqi", "category", "pm2_5", "pm10", "o3", "no2"),
+		append(row,
+			r.Time.Format(time.RFC3339), formatOptInt(r.AQI), r.Category,
+			formatOptFloat(r.PM25), formatOptFloat(r.PM10), formatOptFloat(r.O3), formatOptFloat(r.NO2),
+		),
+	}
+}
+
+func (r *AirQualityResponse) summary() string {
+	s := "AQI unknown"
+	if r.AQI != nil {
+		s = fmt.Sprintf("AQI %d (%s)", *r.AQI, strings.ReplaceAll(r.Category, "_", " "))
+	}
+	if r.PM25 != nil {
+		s += fmt.Sprintf(", PM2.5 %.1f μg/m3", *r.PM25)
+	}
+	if r.Provenance != nil {
+		s += " (" + r.Provenance.Source + ")"
+	}
+	return s
+}
+
+// GetAirQuality returns the US EPA air quality index and the pollutant
+// concentrations in μg/m3 for the specified city. Data retrieval follows the
+// same order as GetWeather. The response is rendered in the negotiated format.
+func (s *Service) GetAirQuality(ctx echo.Context) error {
+	if err := validateCity(ctx); err != nil {
+		return err
+	}
+
+	f, err := negotiate(ctx)
+	if err != nil {
+		return err
+	}
+
+	res, err := fetch(ctx.Request().Context(), s.chain(), s.airCache, "air quality", func(c context.Context, p provider) (*weather.AirQuality, error) {
+		return p.airQuality(c, location)
+	})
+	if err != nil {
+		return err
+	}
+
+	aq := res.value
+	return render(ctx, f, http.StatusOK, &AirQualityResponse{
+		Provenance: newProvenanceResponse(res, &aq.Time),
+		Time:       aq.Time,
+		AQI:        aq.AQI,
+		Category:   string(aq.Category),
+		PM25:       aq.PM25,
+		PM10:       aq.PM10,
+		O3:         aq.O3,
+		NO2:        aq.NO2,
+	})
+}
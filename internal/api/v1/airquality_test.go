@@ -0,0 +1,45 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshjon/sydneyweather/internal/weather"
+)
+
+func TestService_GetAirQuality(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/v2/air-quality?city="+wantCity, nil)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+
+	// weatherstack does not report air quality so the chain falls through to OpenWeather
+	s := newTestService(&mockWeatherStackClient{}, &mockOpenWeatherClient{})
+
+	err := s.GetAirQuality(ctx)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp AirQualityResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, ptr(102), resp.AQI)
+	require.Equal(t, string(weather.AirQualityUnhealthyForSensitiveGroups), resp.Category)
+	require.Equal(t, ptr(wantPM25), resp.PM25)
+	require.Nil(t, resp.PM10)
+}
+
+func TestService_GetAirQuality_unavailableError(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/v2/air-quality?city="+wantCity, nil)
+	ctx := e.NewContext(req, httptest.NewRecorder())
+
+	s := newTestService(&mockWeatherStackClient{}, &mockOpenWeatherClient{wantErr: true})
+
+	err := s.GetAirQuality(ctx)
+	require.EqualError(t, err, "code=503, message=Service Unavailable, internal=no provider could return air quality")
+}
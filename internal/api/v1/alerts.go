@@ -0,0 +1,178 @@
+package api
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/joshjon/sydneyweather/internal/weather"
+)
+
+type AlertsResponse struct {
+	XMLName    xml.Name            `json:"-" xml:"alerts"`
+	Provenance *ProvenanceResponse `json:"provenance" xml:"provenance"`
+	Alerts     []AlertResponse     `json:"alerts" xml:"alert"`
+}
+
+type AlertResponse struct {
+	ID          string    `json:"id" xml:"id"`
+	Headline    string    `json:"headline" xml:"headline"`
+	Description string    `json:"description" xml:"description"`
+	Source      string    `json:"source" xml:"source"`
+	Severity    string    `json:"severity" xml:"severity"`
+	ValidFrom   time.Time `json:"valid_from" xml:"valid_from"`
+	ValidUntil  time.Time `json:"valid_until" xml:"valid_until"`
+	Tags        []string  `json:"tags" xml:"tags>tag"`
+	FirstSeen   time.Time `json:"first_seen" xml:"first_seen"`
+}
+
+// csvRecords returns a row for each alert with the provenance repeated in the
+// leading columns. Tags are separated by semicolons.
+func (r *AlertsResponse) csvRecords() [][]string {
+	header, provenance := r.Provenance.csvFields()
+	records := [][]string{append(header,
+		"id", "headline", "description", "source", "severity", "valid_from", "valid_until", "tags", "first_seen",
+	)}
+
+	for _, a := range r.Alerts {
+		row := append(append([]string(nil), provenance...),
+			a.ID, a.Headline, a.Description, a.Source, a.Severity,
+			a.ValidFrom.Format(time.RFC3339), a.ValidUntil.Format(time.RFC3339),
+			strings.Join(a.Tags, ";"), a.FirstSeen.Format(time.RFC3339),
+		)
+		records = append(records, row)
+	}
+
+	return records
+}
+
+func (r *AlertsResponse) summary() string {
+	var s string
+	switch len(r.Alerts) {
+	case 0:
+		s = "No active alerts"
+	case 1:
+		s = "1 active alert: "
+	default:
+		s = fmt.Sprintf("%d active alerts: ", len(r.Alerts))
+	}
+
+	headlines := make([]string, 0, len(r.Alerts))
+	for _, a := range r.Alerts {
+		headlines = append(headlines, fmt.Sprintf("%s (%s)", a.Headline, a.Severity))
+	}
+	s += strings.Join(headlines, "; ")
+
+	if r.Provenance != nil {
+		s += " (" + r.Provenance.Source + ")"
+	}
+	return s
+}
+
+// GetAlerts returns the active severe weather alerts for the specified city.
+// Providers that do not report alerts are skipped in the chain. The response is
+// rendered in the negotiated format.
+func (s *Service) GetAlerts(ctx echo.Context) error {
+	if err := validateCity(ctx); err != nil {
+		return err
+	}
+
+	f, err := negotiate(ctx)
+	if err != nil {
+		return err
+	}
+
+	res, err := fetch(ctx.Request().Context(), s.chain(), s.alertsCache, "alerts", func(c context.Context, p provider) (*[]weather.Alert, error) {
+		alerts, err := p.alerts(c, location)
+		if err != nil {
+			return nil, err
+		}
+		return &alerts, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	tracked := s.alertLog.merge(*res.value, time.Now())
+
+	resp := AlertsResponse{
+		Provenance: newProvenanceResponse(res, nil),
+		Alerts:     make([]AlertResponse, 0, len(tracked)),
+	}
+	for _, a := range tracked {
+		resp.Alerts = append(resp.Alerts, AlertResponse{
+			ID:          a.ID(),
+			Headline:    a.Headline,
+			Description: a.Description,
+			Source:      a.Source,
+			Severity:    string(a.Severity),
+			ValidFrom:   a.Start,
+			ValidUntil:  a.End,
+			Tags:        a.Tags,
+			FirstSeen:   a.firstSeen,
+		})
+	}
+
+	return render(ctx, f, http.StatusOK, &resp)
+}
+
+type trackedAlert struct {
+	weather.Alert
+	firstSeen time.Time
+}
+
+// alertLog de-duplicates alerts across refreshes. Providers report every active
+// alert on each refresh, so the log remembers when each alert was first seen to
+// let consumers distinguish new alerts from ones already reported.
+type alertLog struct {
+	mu        sync.Mutex
+	firstSeen map[string]time.Time
+}
+
+// newAlertLog creates a new alert log.
+func newAlertLog() *alertLog {
+	return &alertLog{
+		firstSeen: make(map[string]time.Time),
+	}
+}
+
+// merge records the alerts from a refresh and returns those that have not
+// expired, without duplicates, ordered by start time. Alerts that are no longer
+// reported are forgotten.
+func (l *alertLog) merge(alerts []weather.Alert, now time.Time) []trackedAlert {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	firstSeen := make(map[string]time.Time, len(alerts))
+	tracked := make([]trackedAlert, 0, len(alerts))
+
+	for _, a := range alerts {
+		id := a.ID()
+		if _, ok := firstSeen[id]; ok || !a.End.After(now) {
+			continue
+		}
+
+		seen, ok := l.firstSeen[id]
+		if !ok {
+			seen = now
+		}
+		firstSeen[id] = seen
+
+		tracked = append(tracked, trackedAlert{Alert: a, firstSeen: seen})
+	}
+
+	l.firstSeen = firstSeen
+
+	sort.SliceStable(tracked, func(i, j int) bool {
+		return tracked[i].Start.Before(tracked[j].Start)
+	})
+
+	return tracked
+}
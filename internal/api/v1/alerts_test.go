@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshjon/sydneyweather/internal/weather"
+)
+
+func TestService_GetAlerts(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/v2/weather/alerts?city="+wantCity, nil)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+
+	// weatherstack does not report alerts so the chain falls through to OpenWeather
+	s := newTestService(&mockWeatherStackClient{}, &mockOpenWeatherClient{})
+
+	err := s.GetAlerts(ctx)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp AlertsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Alerts, 1)
+	require.Equal(t, wantAlertHeadline, resp.Alerts[0].Headline)
+	require.Equal(t, wantAlertSource, resp.Alerts[0].Source)
+	require.Equal(t, string(weather.SeveritySevere), resp.Alerts[0].Severity)
+	require.NotEmpty(t, resp.Alerts[0].ID)
+}
+
+func TestService_GetAlerts_unavailableError(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/v2/weather/alerts?city="+wantCity, nil)
+	ctx := e.NewContext(req, httptest.NewRecorder())
+
+	s := newTestService(&mockWeatherStackClient{}, &mockOpenWeatherClient{wantErr: true})
+
+	err := s.GetAlerts(ctx)
+	require.EqualError(t, err, "code=503, message=Service Unavailable, internal=no provider could return alerts")
+}
+
+func TestAlertLog_merge(t *testing.T) {
+	now := time.Now()
+	flood := weather.Alert{Source: "some-source", Headline: "Flood Watch", Start: now.Add(-time.Hour), End: now.Add(time.Hour)}
+	storm := weather.Alert{Source: "some-source", Headline: "Severe Thunderstorm Warning", Start: now, End: now.Add(time.Hour)}
+	expired := weather.Alert{Source: "some-source", Headline: "Fire Weather Warning", Start: now.Add(-2 * time.Hour), End: now}
+
+	l := newAlertLog()
+
+	// Duplicates within a refresh and expired alerts are dropped
+	got := l.merge([]weather.Alert{flood, flood, expired}, now)
+	require.Len(t, got, 1)
+	require.Equal(t, flood.ID(), got[0].ID())
+	require.Equal(t, now, got[0].firstSeen)
+
+	// Alerts seen in a previous refresh keep their first seen time
+	later := now.Add(time.Minute)
+	got = l.merge([]weather.Alert{storm, flood}, later)
+	require.Len(t, got, 2)
+	require.Equal(t, flood.ID(), got[0].ID())
+	require.Equal(t, now, got[0].firstSeen)
+	require.Equal(t, storm.ID(), got[1].ID())
+	require.Equal(t, later, got[1].firstSeen)
+
+	// Alerts no longer reported are forgotten
+	got = l.merge([]weather.Alert{storm}, later)
+	require.Len(t, got, 1)
+	got = l.merge([]weather.Alert{flood}, later.Add(time.Minute))
+	require.Equal(t, later.Add(time.Minute), got[0].firstSeen)
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/joshjon/sydneyweather/internal/astronomy"
+	"github.com/joshjon/sydneyweather/internal/logging"
+	"github.com/joshjon/sydneyweather/internal/weather"
+)
+
+// astronomyTolerance is the maximum difference between a computed and provider
+// reported sunrise or sunset for them to be considered consistent. weatherstack
+// reports times to the minute and providers may use different algorithms.
+const astronomyTolerance = 5 * time.Minute
+
+type AstronomyResponse struct {
+	XMLName          xml.Name                `json:"-" xml:"astronomy"`
+	Date             string                  `json:"date" xml:"date"` // Local date in YYYY-MM-DD format
+	Sunrise          *time.Time              `json:"sunrise" xml:"sunrise"`
+	Sunset           *time.Time              `json:"sunset" xml:"sunset"`
+	SolarNoon        time.Time               `json:"solar_noon" xml:"solar_noon"`
+	CivilDawn        *time.Time              `json:"civil_dawn" xml:"civil_dawn"`
+	CivilDusk        *time.Time              `json:"civil_dusk" xml:"civil_dusk"`
+	DayLengthSeconds int                     `json:"day_length_seconds" xml:"day_length_seconds"`
+	MoonPhase        string                  `json:"moon_phase" xml:"moon_phase"`
+	MoonIllumination float64                 `json:"moon_illumination" xml:"moon_illumination"`
+	MoonAge          float64                 `json:"moon_age_days" xml:"moon_age_days"`
+	ProviderCheck    *AstronomyCheckResponse `json:"provider_check" xml:"provider_check"`
+}
+
+// AstronomyCheckResponse compares the computed sunrise and sunset with those
+// reported by a provider. Differences are provider minus computed.
+type AstronomyCheckResponse struct {
+	SunriseDiffSeconds *int `json:"sunrise_difference_seconds" xml:"sunrise_difference_seconds"`
+	SunsetDiffSeconds  *int `json:"sunset_difference_seconds" xml:"sunset_difference_seconds"`
+	Consistent         bool `json:"consistent" xml:"consistent"`
+}
+
+// csvRecords flattens the provider check into the trailing columns, which are
+// empty when there was nothing to check against.
+func (r *AstronomyResponse) csvRecords() [][]string {
+	row := []string{
+		r.Date, formatOptTime(r.Sunrise), formatOptTime(r.Sunset), r.SolarNoon.Format(time.RFC3339),
+		formatOptTime(r.CivilDawn), formatOptTime(r.CivilDusk), strconv.Itoa(r.DayLengthSeconds),
+		r.MoonPhase, formatFloat(r.MoonIllumination), formatFloat(r.MoonAge),
+	}
+
+	if c := r.ProviderCheck; c != nil {
+		row = append(row, formatOptInt(c.SunriseDiffSeconds), formatOptInt(c.SunsetDiffSeconds), strconv.FormatBool(c.Consistent))
+	} else {
+		row = append(row, "", "", "")
+	}
+
+	return [][]string{
+		{
+			"date", "sunrise", "sunset", "solar_noon", "civil_dawn", "civil_dusk", "day_length_seconds",
+			"moon_phase", "moon_illumination", "moon_age_days",
+			"sunrise_difference_seconds", "sunset_difference_seconds", "consistent",
+		},
+		row,
+	}
+}
+
+// summary gives local clock times. The sun does not rise or set on some days
+// near the poles.
+func (r *AstronomyResponse) summary() string {
+	clock := func(t *time.Time) string {
+		if t == nil {
+			return "none"
+		}
+		return t.In(location.Zone).Format("15:04")
+	}
+	phase := strings.ReplaceAll(r.MoonPhase, "_", " ")
+	return fmt.Sprintf("%s: sunrise %s, sunset %s, moon phase %s", r.Date, clock(r.Sunrise), clock(r.Sunset), phase)
+}
+
+// GetAstronomy returns the sunrise, sunset, solar noon, civil twilight and moon
+// phase for the specified city on the date specified by the optional 'date'
+// query param, which defaults to today. Values are computed locally without
+// querying a provider. If a cached forecast includes the date, its sunrise and
+// sunset are cross-checked against the computed values. The response is
+// rendered in the negotiated format.
+func (s *Service) GetAstronomy(ctx echo.Context) error {
+	if err := validateCity(ctx); err != nil {
+		return err
+	}
+
+	f, err := negotiate(ctx)
+	if err != nil {
+		return err
+	}
+
+	date := time.Now().In(location.Zone)
+	if param := ctx.QueryParam("date"); param != "" {
+		if date, err = time.ParseInLocation(dateLayout, param, location.Zone); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "query param 'date' must be in YYYY-MM-DD format")
+		}
+	}
+
+	sun := astronomy.Sun(date, location.Lat, location.Lon)
+	moon := astronomy.Moon(sun.SolarNoon)
+
+	resp := &AstronomyResponse{
+		Date:             date.Format(dateLayout),
+		Sunrise:          sun.Sunrise,
+		Sunset:           sun.Sunset,
+		SolarNoon:        sun.SolarNoon,
+		CivilDawn:        sun.CivilDawn,
+		CivilDusk:        sun.CivilDusk,
+		DayLengthSeconds: int(sun.DayLength.Seconds()),
+		MoonPhase:        string(moon.Name),
+		MoonIllumination: moon.Illumination,
+		MoonAge:          moon.Age,
+	}
+
+	if res, ok := s.forecastCache.get(); ok {
+		resp.ProviderCheck = crossCheckSun(sun, res.value, resp.Date)
+		if check := resp.ProviderCheck; check != nil && !check.Consistent {
+			logging.FromContext(ctx.Request().Context()).Warn("provider sunrise/sunset differs from computed value",
+				"date", resp.Date, "provider", res.source,
+				"sunrise_diff_seconds", check.SunriseDiffSeconds, "sunset_diff_seconds", check.SunsetDiffSeconds)
+		}
+	}
+
+	return render(ctx, f, http.StatusOK, resp)
+}
+
+// crossCheckSun compares the computed sunrise and sunset with those of the
+// forecast day matching date. Nil is returned if the forecast does not include
+// the date or its sunrise and sunset.
+func crossCheckSun(sun astronomy.SunTimes, forecast *weather.Forecast, date string) *AstronomyCheckResponse {
+	for _, day := range forecast.Daily {
+		if day.Date.Format(dateLayout) != date || (day.Sunrise == nil && day.Sunset == nil) {
+			continue
+		}
+
+		check := &AstronomyCheckResponse{Consistent: true}
+		compare := func(reported *time.Time, computed *time.Time) *int {
+			if reported == nil || computed == nil {
+				return nil
+			}
+			diff := reported.Sub(*computed)
+			if diff > astronomyTolerance || diff < -astronomyTolerance {
+				check.Consistent = false
+			}
+			seconds := int(diff.Seconds())
+			return &seconds
+		}
+
+		check.SunriseDiffSeconds = compare(day.Sunrise, sun.Sunrise)
+		check.SunsetDiffSeconds = compare(day.Sunset, sun.Sunset)
+
+		return check
+	}
+
+	return nil
+}
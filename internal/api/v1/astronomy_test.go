@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshjon/sydneyweather/internal/astronomy"
+	"github.com/joshjon/sydneyweather/internal/weather"
+)
+
+func TestService_GetAstronomy(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/v2/astronomy?city="+wantCity+"&date=2022-06-21", nil)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+
+	// Providers are never queried
+	s := newTestService(&mockWeatherStackClient{wantErr: true}, &mockOpenWeatherClient{wantErr: true})
+
+	err := s.GetAstronomy(ctx)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp AstronomyResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, "2022-06-21", resp.Date)
+	require.WithinDuration(t, time.Date(2022, 6, 21, 7, 0, 0, 0, location.Zone), *resp.Sunrise, 2*time.Minute)
+	require.WithinDuration(t, time.Date(2022, 6, 21, 16, 53, 0, 0, location.Zone), *resp.Sunset, 2*time.Minute)
+	require.Equal(t, string(astronomy.LastQuarter), resp.MoonPhase)
+	require.Nil(t, resp.ProviderCheck)
+}
+
+func TestService_GetAstronomy_invalidDate(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/v2/astronomy?city="+wantCity+"&date=21-06-2022", nil)
+	ctx := e.NewContext(req, httptest.NewRecorder())
+
+	s := newTestService(&mockWeatherStackClient{}, &mockOpenWeatherClient{})
+
+	err := s.GetAstronomy(ctx)
+	require.EqualError(t, err, "code=400, message=query param 'date' must be in YYYY-MM-DD format")
+}
+
+func TestCrossCheckSun(t *testing.T) {
+	date := time.Date(2022, 6, 21, 0, 0, 0, 0, location.Zone)
+	sun := astronomy.Sun(date, location.Lat, location.Lon)
+
+	tests := []struct {
+		name           string
+		sunrise        time.Time
+		sunset         time.Time
+		wantConsistent bool
+	}{
+		{
+			name:           "consistent",
+			sunrise:        sun.Sunrise.Add(time.Minute),
+			sunset:         sun.Sunset.Add(-time.Minute),
+			wantConsistent: true,
+		},
+		{
+			name:           "inconsistent",
+			sunrise:        sun.Sunrise.Add(time.Hour),
+			sunset:         *sun.Sunset,
+			wantConsistent: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forecast := &weather.Forecast{
+				Daily: []weather.DailyForecast{
+					{Date: date, Sunrise: &tt.sunrise, Sunset: &tt.sunset},
+				},
+			}
+			check := crossCheckSun(sun, forecast, "2022-06-21")
+			require.NotNil(t, check)
+			require.Equal(t, tt.wantConsistent, check.Consistent)
+			require.Equal(t, int(tt.sunrise.Sub(*sun.Sunrise).Seconds()), *check.SunriseDiffSeconds)
+		})
+	}
+
+	// Forecast does not include the date
+	require.Nil(t, crossCheckSun(sun, &weather.Forecast{}, "2022-06-21"))
+}
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"container/list"
+	"sync"
 	"time"
 )
 
@@ -8,19 +10,21 @@ import (
 // duration. A map cache is more suitable in most circumstances, however, this
 // service only supports a single city which means a map is unnecessary.
 // The cache value is never cleaned up in order to support retrieval of a stale
-// value.
+// value. It is safe for concurrent use since metrics are read while requests
+// are served. The duration can be changed while the value is cached, in which
+// case the value expires the new duration after it was put.
 type valueCache[T any] struct {
-	value      *T
-	duration   time.Duration
-	expiration int64
+	mu       sync.RWMutex
+	value    *T
+	duration time.Duration
+	putAt    time.Time
 }
 
 // newValueCache creates a new value cache.
 func newValueCache[T any](duration time.Duration) *valueCache[T] {
 	cache := &valueCache[T]{
-		value:      nil,
-		duration:   duration,
-		expiration: 0,
+		value:    nil,
+		duration: duration,
 	}
 
 	return cache
@@ -28,12 +32,16 @@ func newValueCache[T any](duration time.Duration) *valueCache[T] {
 
 // put inserts a new value into the cache. Any existing value will be overridden.
 func (c *valueCache[T]) put(value *T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.value = value
-	c.expiration = time.Now().Add(c.duration).UnixNano()
+	c.putAt = time.Now()
 }
 
 // get returns the cache value.
 func (c *valueCache[T]) get() (*T, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	if c.value == nil {
 		return nil, false
 	}
@@ -42,5 +50,79 @@ func (c *valueCache[T]) get() (*T, bool) {
 
 // expired returns a boolean that indicates if the cache value is expired.
 func (c *valueCache[T]) expired() bool {
-	return c.expiration == 0 || c.expiration > 0 && time.Now().UnixNano() > c.expiration
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.putAt.IsZero() || time.Since(c.putAt) > c.duration
+}
+
+// ttl returns how long values are cached for.
+func (c *valueCache[T]) ttl() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.duration
+}
+
+// setTTL changes how long values are cached for.
+func (c *valueCache[T]) setTTL(duration time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.duration = duration
+}
+
+// mapCache is a cache that stores values by key in memory until it holds
+// maxEntries, after which the least recently used value is evicted to make
+// room. It is only suitable for values that never change, such as historical
+// observations. It is safe for concurrent use.
+type mapCache[K comparable, V any] struct {
+	mu         sync.Mutex
+	maxEntries int
+	order      *list.List // Entries, most recently used first
+	values     map[K]*list.Element
+}
+
+type mapEntry[K comparable, V any] struct {
+	key   K
+	value *V
+}
+
+// newMapCache creates a new map cache that holds up to maxEntries values.
+func newMapCache[K comparable, V any](maxEntries int) *mapCache[K, V] {
+	return &mapCache[K, V]{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		values:     make(map[K]*list.Element),
+	}
+}
+
+// put inserts a new value into the cache for the key. Any existing value will be
+// overridden.
+func (c *mapCache[K, V]) put(key K, value *V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.values[key]; ok {
+		el.Value.(*mapEntry[K, V]).value = value
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.values[key] = c.order.PushFront(&mapEntry[K, V]{key: key, value: value})
+	if c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.values, oldest.Value.(*mapEntry[K, V]).key)
+	}
+}
+
+// get returns the cache value for the key.
+func (c *mapCache[K, V]) get(key K) (*V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.values[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*mapEntry[K, V]).value, true
 }
@@ -28,3 +28,51 @@ func TestValueCache(t *testing.T) {
 	require.True(t, ok)
 	require.Equal(t, wantVal, *gotVal)
 }
+
+func TestValueCache_setTTL(t *testing.T) {
+	c := newValueCache[string](time.Hour)
+	wantVal := "some-val"
+	c.put(&wantVal)
+	require.False(t, c.expired())
+
+	// Applies to the cached value
+	c.setTTL(time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	require.True(t, c.expired())
+	require.Equal(t, time.Nanosecond, c.ttl())
+
+	c.setTTL(time.Hour)
+	require.False(t, c.expired())
+}
+
+func TestMapCache(t *testing.T) {
+	c := newMapCache[string, string](2)
+	wantVal := "some-val"
+	c.put("some-key", &wantVal)
+
+	gotVal, ok := c.get("some-key")
+	require.True(t, ok)
+	require.Equal(t, wantVal, *gotVal)
+
+	_, ok = c.get("other-key")
+	require.False(t, ok)
+}
+
+func TestMapCache_evict(t *testing.T) {
+	c := newMapCache[string, string](2)
+	a, b, d := "a", "b", "d"
+	c.put("a", &a)
+	c.put("b", &b)
+
+	// Getting a makes b the least recently used
+	_, ok := c.get("a")
+	require.True(t, ok)
+	c.put("d", &d)
+
+	_, ok = c.get("b")
+	require.False(t, ok)
+	_, ok = c.get("a")
+	require.True(t, ok)
+	_, ok = c.get("d")
+	require.True(t, ok)
+}
@@ -0,0 +1,383 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/joshjon/sydneyweather/internal/logging"
+	"github.com/joshjon/sydneyweather/internal/weather"
+)
+
+// Outlier detection defaults used when OutlierConfig values are not set.
+const (
+	defaultOutlierWindow               = 6
+	defaultOutlierTemperatureThreshold = 3  // °C
+	defaultOutlierWindSpeedThreshold   = 15 // km/h
+
+	// minConsensusReadings is the fewest readings that can single out an
+	// outlier. The median of two readings is their midpoint, which both
+	// deviate from equally.
+	minConsensusReadings = 3
+)
+
+// OutlierConfig configures cross-provider outlier detection. Sampling is
+// disabled when Interval is zero.
+type OutlierConfig struct {
+	Interval             time.Duration
+	Window               int
+	TemperatureThreshold float64
+	WindSpeedThreshold   float64
+}
+
+type DiagnosticsResponse struct {
+	SampledAt *time.Time                    `json:"sampled_at"`
+	Chain     []string                      `json:"chain"`
+	Providers []ProviderDiagnosticsResponse `json:"providers"`
+}
+
+type ProviderDiagnosticsResponse struct {
+	Name                   string                    `json:"name"`
+	Flagged                bool                      `json:"flagged"`
+	MeanTempDeviation      *float64                  `json:"mean_temperature_deviation_degrees"`
+	MeanWindSpeedDeviation *float64                  `json:"mean_wind_speed_deviation_kmh"`
+	TemperatureThreshold   float64                   `json:"temperature_threshold_degrees"`
+	WindSpeedThreshold     float64                   `json:"wind_speed_threshold_kmh"`
+	Samples                []DeviationSampleResponse `json:"samples"`
+}
+
+type DeviationSampleResponse struct {
+	Time               time.Time `json:"time"`
+	Location           string    `json:"location"`
+	TempDegrees        *float64  `json:"temperature_degrees"`
+	WindSpeed          *float64  `json:"wind_speed_kmh"`
+	TempDeviation      *float64  `json:"temperature_deviation_degrees"`
+	WindSpeedDeviation *float64  `json:"wind_speed_deviation_kmh"`
+	Error              string    `json:"error,omitempty"`
+}
+
+// GetProviderDiagnostics returns how far each provider's recent readings
+// deviate from the consensus and the resulting order of the provider chain.
+func (s *Service) GetProviderDiagnostics(ctx echo.Context) error {
+	return ctx.JSON(http.StatusOK, s.outliers.diagnostics())
+}
+
+// Monitor samples every provider at the configured interval to detect outliers
+// until the context is done. Samples are limited by the provider timeout and
+// recorded in the provider metrics and health like any other call.
+func (s *Service) Monitor(ctx context.Context) {
+	s.outliers.run(ctx, s.chain)
+}
+
+// deviationSample is a provider reading and its deviation from the consensus.
+// Deviations are nil when the provider failed or no consensus was possible.
+// Providers with an open circuit are not called and fail with errCircuitOpen.
+type deviationSample struct {
+	time               time.Time
+	location           string
+	obs                *weather.Observation
+	tempDeviation      *float64
+	windSpeedDeviation *float64
+	err                error
+}
+
+// outlierMonitor periodically samples the current conditions from every
+// provider and compares each reading to the consensus, which is the median of
+// all successful readings. A provider whose mean deviation over the window
+// exceeds a threshold is flagged and demoted to the end of the chain until its
+// readings recover. A consensus needs at least three readings, so nothing is
+// flagged while fewer providers are available.
+type outlierMonitor struct {
+	cfg       OutlierConfig
+	locations []weather.Location
+	validator *weather.Validator
+
+	mu        sync.RWMutex
+	providers []provider
+	order     []provider
+	samples   map[string][]deviationSample
+	flagged   map[string]bool
+	sampledAt time.Time
+}
+
+// newOutlierMonitor creates a new outlier monitor for the providers in their
+// configured order. Unset config values are defaulted.
+func newOutlierMonitor(cfg OutlierConfig, providers []provider, locations []weather.Location, validator *weather.Validator) *outlierMonitor {
+	if cfg.Window <= 0 {
+		cfg.Window = defaultOutlierWindow
+	}
+	if cfg.TemperatureThreshold <= 0 {
+		cfg.TemperatureThreshold = defaultOutlierTemperatureThreshold
+	}
+	if cfg.WindSpeedThreshold <= 0 {
+		cfg.WindSpeedThreshold = defaultOutlierWindSpeedThreshold
+	}
+
+	return &outlierMonitor{
+		cfg:       cfg,
+		locations: locations,
+		validator: validator,
+		providers: providers,
+		order:     providers,
+		samples:   make(map[string][]deviationSample),
+		flagged:   make(map[string]bool),
+	}
+}
+
+// run samples the providers at the configured interval until the context is
+// done. Chain supplies the timeout of each call and where calls are recorded.
+func (m *outlierMonitor) run(ctx context.Context, chain func() *providerChain) {
+	if m.cfg.Interval <= 0 {
+		return
+	}
+
+	// Sampling fewer providers would spend their quota without flagging any
+	m.mu.RLock()
+	providers := len(m.providers)
+	m.mu.RUnlock()
+	if providers < minConsensusReadings {
+		logging.FromContext(ctx).Warn("outlier detection needs at least three providers and is disabled",
+			"providers", providers)
+		return
+	}
+
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		m.sample(ctx, chain(), time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// chain returns the providers with flagged providers moved to the end.
+func (m *outlierMonitor) chain() []provider {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.order
+}
+
+// reorder changes the configured order of the providers. Flagged providers
+// remain at the end of the chain.
+func (m *outlierMonitor) reorder(providers []provider, logger *slog.Logger) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.providers = providers
+	m.updateFlags(logger)
+}
+
+// sample queries every provider in configured order for each location, records
+// the deviations from the consensus and updates which providers are flagged.
+// Calls use the timeout, metrics and health of the chain, not its order.
+func (m *outlierMonitor) sample(ctx context.Context, c *providerChain, now time.Time) {
+	m.mu.RLock()
+	providers := m.providers
+	m.mu.RUnlock()
+
+	var samples []deviationSample
+	for _, loc := range m.locations {
+		samples = append(samples, m.sampleLocation(ctx, c, providers, loc, now)...)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Samples hold a run of providers in configured order for each location
+	for i, p := range providers {
+		for j := i; j < len(samples); j += len(providers) {
+			if errors.Is(samples[j].err, errNotSupported) {
+				continue
+			}
+			history := append(m.samples[p.name()], samples[j])
+			if len(history) > m.cfg.Window {
+				history = history[len(history)-m.cfg.Window:]
+			}
+			m.samples[p.name()] = history
+		}
+	}
+
+	m.sampledAt = now
+	m.updateFlags(logging.FromContext(ctx))
+}
+
+// sampleLocation returns a sample for each provider in configured order.
+func (m *outlierMonitor) sampleLocation(ctx context.Context, c *providerChain, providers []provider, loc weather.Location, now time.Time) []deviationSample {
+	samples := make([]deviationSample, len(providers))
+	var temps, winds []float64
+
+	for i, p := range providers {
+		obs, err := m.current(ctx, c, p, loc)
+		samples[i] = deviationSample{time: now, location: loc.Name, err: err}
+		if err != nil {
+			continue
+		}
+		samples[i].obs = obs
+		temps, winds = append(temps, obs.Temperature), append(winds, obs.WindSpeed)
+	}
+
+	if len(temps) < minConsensusReadings {
+		return samples
+	}
+
+	consensusTemp, consensusWind := median(temps), median(winds)
+	for i := range samples {
+		if samples[i].obs == nil {
+			continue
+		}
+		tempDev := math.Abs(samples[i].obs.Temperature - consensusTemp)
+		windDev := math.Abs(samples[i].obs.WindSpeed - consensusWind)
+		samples[i].tempDeviation, samples[i].windSpeedDeviation = &tempDev, &windDev
+	}
+
+	return samples
+}
+
+// current returns the provider's validated reading for the location. The call
+// is skipped while the provider's circuit is open and its outcome is recorded
+// unless the provider does not support current conditions.
+func (m *outlierMonitor) current(ctx context.Context, c *providerChain, p provider, loc weather.Location) (*weather.Observation, error) {
+	if !c.health.allow(p, operationCurrent) {
+		return nil, errCircuitOpen
+	}
+
+	start := time.Now()
+	obs, err := call(ctx, c.timeout, p, func(ctx context.Context, p provider) (*weather.Observation, error) {
+		obs, err := p.current(ctx, loc)
+		if err != nil {
+			return nil, err
+		}
+		if err = m.validator.Validate(loc.Name, obs); err != nil {
+			return nil, err
+		}
+		return obs, nil
+	})
+	if errors.Is(err, errNotSupported) {
+		return nil, err
+	}
+
+	duration := time.Since(start)
+	c.metrics.observeProvider(p, operationCurrent, err, duration)
+	c.health.record(p, operationCurrent, err, duration, time.Now())
+	return obs, err
+}
+
+// updateFlags flags providers whose mean deviation exceeds a threshold and
+// reorders the chain. The caller must hold the lock.
+func (m *outlierMonitor) updateFlags(logger *slog.Logger) {
+	order := make([]provider, 0, len(m.providers))
+	var demoted []provider
+
+	for _, p := range m.providers {
+		tempDev, windDev := meanDeviations(m.samples[p.name()])
+		flagged := (tempDev != nil && *tempDev > m.cfg.TemperatureThreshold) ||
+			(windDev != nil && *windDev > m.cfg.WindSpeedThreshold)
+
+		if flagged != m.flagged[p.name()] {
+			if flagged {
+				logger.Warn("provider flagged as an outlier and demoted", "provider", p.name())
+			} else {
+				logger.Info("provider no longer an outlier and restored", "provider", p.name())
+			}
+		}
+		m.flagged[p.name()] = flagged
+
+		if flagged {
+			demoted = append(demoted, p)
+		} else {
+			order = append(order, p)
+		}
+	}
+
+	m.order = append(order, demoted...)
+}
+
+func (m *outlierMonitor) diagnostics() *DiagnosticsResponse {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	resp := &DiagnosticsResponse{
+		Chain:     make([]string, 0, len(m.order)),
+		Providers: make([]ProviderDiagnosticsResponse, 0, len(m.providers)),
+	}
+	if !m.sampledAt.IsZero() {
+		sampledAt := m.sampledAt
+		resp.SampledAt = &sampledAt
+	}
+
+	for _, p := range m.order {
+		resp.Chain = append(resp.Chain, p.name())
+	}
+
+	for _, p := range m.providers {
+		history := m.samples[p.name()]
+		diag := ProviderDiagnosticsResponse{
+			Name:                 p.name(),
+			Flagged:              m.flagged[p.name()],
+			TemperatureThreshold: m.cfg.TemperatureThreshold,
+			WindSpeedThreshold:   m.cfg.WindSpeedThreshold,
+			Samples:              make([]DeviationSampleResponse, 0, len(history)),
+		}
+		diag.MeanTempDeviation, diag.MeanWindSpeedDeviation = meanDeviations(history)
+
+		for _, sample := range history {
+			sampleResp := DeviationSampleResponse{
+				Time:               sample.time,
+				Location:           sample.location,
+				TempDeviation:      sample.tempDeviation,
+				WindSpeedDeviation: sample.windSpeedDeviation,
+			}
+			if sample.obs != nil {
+				sampleResp.TempDegrees, sampleResp.WindSpeed = &sample.obs.Temperature, &sample.obs.WindSpeed
+			}
+			if sample.err != nil {
+				sampleResp.Error = logging.Redact(sample.err.Error())
+			}
+			diag.Samples = append(diag.Samples, sampleResp)
+		}
+
+		resp.Providers = append(resp.Providers, diag)
+	}
+
+	return resp
+}
+
+// meanDeviations returns the mean temperature and wind speed deviations of the
+// samples that could be compared to a consensus, or nil if there are none.
+func meanDeviations(samples []deviationSample) (*float64, *float64) {
+	var tempSum, windSum float64
+	var n int
+	for _, sample := range samples {
+		if sample.tempDeviation == nil {
+			continue
+		}
+		tempSum += *sample.tempDeviation
+		windSum += *sample.windSpeedDeviation
+		n++
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	tempMean, windMean := tempSum/float64(n), windSum/float64(n)
+	return &tempMean, &windMean
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
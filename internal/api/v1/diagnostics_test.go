@@ -0,0 +1,232 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshjon/sydneyweather/internal/weather"
+)
+
+func TestOutlierMonitor_sample(t *testing.T) {
+	a := &stubProvider{n: "a", obs: &weather.Observation{Temperature: 20, WindSpeed: 10}}
+	b := &stubProvider{n: "b", obs: &weather.Observation{Temperature: 30, WindSpeed: 10}}
+	c := &stubProvider{n: "c", obs: &weather.Observation{Temperature: 21, WindSpeed: 12}}
+	m := newOutlierMonitor(OutlierConfig{Window: 2}, []provider{a, b, c}, []weather.Location{location}, weather.NewValidator(weather.Limits{}, nil))
+
+	require.Equal(t, []string{"a", "b", "c"}, chainNames(m.chain()))
+
+	// b deviates 9°C from the median and is demoted
+	now := time.Now()
+	m.sample(context.Background(), testChain(), now)
+	require.Equal(t, []string{"b"}, flaggedNames(m))
+	require.Equal(t, []string{"a", "c", "b"}, chainNames(m.chain()))
+
+	diag := m.diagnostics()
+	require.Equal(t, now, *diag.SampledAt)
+	require.Equal(t, []string{"a", "c", "b"}, diag.Chain)
+	require.Len(t, diag.Providers, 3)
+	require.True(t, diag.Providers[1].Flagged)
+	require.Equal(t, 9.0, *diag.Providers[1].MeanTempDeviation)
+	require.Equal(t, 0.0, *diag.Providers[1].MeanWindSpeedDeviation)
+	require.Len(t, diag.Providers[1].Samples, 1)
+	require.Equal(t, 30.0, *diag.Providers[1].Samples[0].TempDegrees)
+
+	// A failed sample is recorded without a deviation
+	b.obs, b.err = nil, errors.New("some-error")
+	m.sample(context.Background(), testChain(), now)
+	require.Equal(t, []string{"a", "c", "b"}, chainNames(m.chain()))
+	diag = m.diagnostics()
+	require.Equal(t, "some-error", diag.Providers[1].Samples[1].Error)
+	require.Nil(t, diag.Providers[1].Samples[1].TempDeviation)
+
+	// b is restored once its deviating sample leaves the window
+	b.obs, b.err = &weather.Observation{Temperature: 21, WindSpeed: 10}, nil
+	m.sample(context.Background(), testChain(), now)
+	require.Equal(t, []string{"a", "b", "c"}, chainNames(m.chain()))
+	require.Empty(t, flaggedNames(m))
+}
+
+func TestOutlierMonitor_sample_twoProviders(t *testing.T) {
+	a := &stubProvider{n: "a", obs: &weather.Observation{Temperature: 20, WindSpeed: 10}}
+	b := &stubProvider{n: "b", obs: &weather.Observation{Temperature: 30, WindSpeed: 10}}
+	m := newOutlierMonitor(OutlierConfig{}, []provider{a, b}, []weather.Location{location}, weather.NewValidator(weather.Limits{}, nil))
+
+	// Two readings cannot single out an outlier, so neither is flagged
+	m.sample(context.Background(), testChain(), time.Now())
+	require.Empty(t, flaggedNames(m))
+	require.Equal(t, []string{"a", "b"}, chainNames(m.chain()))
+
+	diag := m.diagnostics()
+	require.Nil(t, diag.Providers[0].MeanTempDeviation)
+	require.Nil(t, diag.Providers[1].MeanTempDeviation)
+}
+
+func TestOutlierMonitor_run_twoProviders(t *testing.T) {
+	a := &stubProvider{n: "a", obs: &weather.Observation{Temperature: 20, WindSpeed: 10}}
+	b := &stubProvider{n: "b", obs: &weather.Observation{Temperature: 30, WindSpeed: 10}}
+	m := newOutlierMonitor(OutlierConfig{Interval: time.Millisecond}, []provider{a, b}, []weather.Location{location}, weather.NewValidator(weather.Limits{}, nil))
+
+	// Sampling is skipped since it could never flag a provider
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	m.run(ctx, testChain)
+	require.NoError(t, ctx.Err())
+	require.Nil(t, m.diagnostics().SampledAt)
+}
+
+func TestOutlierMonitor_sample_implausible(t *testing.T) {
+	a := &stubProvider{n: "a", obs: &weather.Observation{Temperature: 20, WindSpeed: 10}}
+	b := &stubProvider{n: "b", obs: &weather.Observation{Temperature: 400, WindSpeed: 10}}
+	c := &stubProvider{n: "c", obs: &weather.Observation{Temperature: 21, WindSpeed: 10}}
+	m := newOutlierMonitor(OutlierConfig{}, []provider{a, b, c}, []weather.Location{location}, weather.NewValidator(weather.Limits{}, nil))
+
+	// Implausible readings are excluded from the consensus, which leaves too
+	// few readings to compare
+	m.sample(context.Background(), testChain(), time.Now())
+	require.Empty(t, flaggedNames(m))
+
+	diag := m.diagnostics()
+	require.Nil(t, diag.Providers[0].MeanTempDeviation)
+	require.Contains(t, diag.Providers[1].Samples[0].Error, weather.ErrImplausibleObservation.Error())
+}
+
+func TestOutlierMonitor_sample_calls(t *testing.T) {
+	a := &stubProvider{n: "a", obs: &weather.Observation{Temperature: 20, WindSpeed: 10}}
+	b := &hangingProvider{stubProvider{n: "b"}}
+	c := &stubProvider{n: "c", err: errors.New("some-error: https://example.com?access_key=some-key")}
+	m := newOutlierMonitor(OutlierConfig{}, []provider{a, b, c}, []weather.Location{location}, weather.NewValidator(weather.Limits{}, nil))
+
+	chain := testChain()
+	chain.timeout = time.Millisecond
+	chain.health = newHealthTracker(HealthConfig{FailureThreshold: 1, Cooldown: time.Hour})
+
+	// Calls are limited by the timeout and recorded in the provider health
+	m.sample(context.Background(), chain, time.Now())
+	diag := m.diagnostics()
+	require.Empty(t, diag.Providers[0].Samples[0].Error)
+	require.Equal(t, context.DeadlineExceeded.Error(), diag.Providers[1].Samples[0].Error)
+	require.Equal(t, "some-error: https://example.com?access_key=REDACTED", diag.Providers[2].Samples[0].Error)
+	require.Equal(t, circuitOpen, chain.health.circuit("b", operationCurrent, time.Now()))
+
+	// Providers with an open circuit are skipped
+	m.sample(context.Background(), chain, time.Now())
+	diag = m.diagnostics()
+	require.Equal(t, errCircuitOpen.Error(), diag.Providers[1].Samples[1].Error)
+	require.Equal(t, errCircuitOpen.Error(), diag.Providers[2].Samples[1].Error)
+}
+
+func TestOutlierMonitor_reorder(t *testing.T) {
+	a := &stubProvider{n: "a", obs: &weather.Observation{Temperature: 20, WindSpeed: 10}}
+	b := &stubProvider{n: "b", obs: &weather.Observation{Temperature: 30, WindSpeed: 10}}
+	c := &stubProvider{n: "c", obs: &weather.Observation{Temperature: 21, WindSpeed: 12}}
+	m := newOutlierMonitor(OutlierConfig{}, []provider{a, b, c}, []weather.Location{location}, weather.NewValidator(weather.Limits{}, nil))
+
+	m.sample(context.Background(), testChain(), time.Now())
+	require.Equal(t, []string{"a", "c", "b"}, chainNames(m.chain()))
+
+	// The flagged provider stays demoted in the new order
+	m.reorder([]provider{b, c, a}, slog.Default())
+	require.Equal(t, []string{"c", "a", "b"}, chainNames(m.chain()))
+}
+
+func TestService_GetProviderDiagnostics(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/admin/diagnostics/providers", nil)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+
+	s := newTestService(&mockWeatherStackClient{}, &mockOpenWeatherClient{})
+	s.outliers.sample(context.Background(), s.chain(), time.Now())
+
+	require.NoError(t, s.GetProviderDiagnostics(ctx))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp DiagnosticsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.NotNil(t, resp.SampledAt)
+	require.Equal(t, []string{"weatherstack", "openweather"}, resp.Chain)
+	require.Len(t, resp.Providers, 2)
+	for _, p := range resp.Providers {
+		require.False(t, p.Flagged)
+		require.Len(t, p.Samples, 1)
+		require.NotNil(t, p.Samples[0].TempDegrees)
+	}
+}
+
+func TestMedian(t *testing.T) {
+	require.Equal(t, 2.0, median([]float64{3, 1, 2}))
+	require.Equal(t, 2.5, median([]float64{4, 1, 2, 3}))
+}
+
+// testChain returns a chain without a timeout to make calls through.
+func testChain() *providerChain {
+	return &providerChain{metrics: newMetrics(), health: newHealthTracker(HealthConfig{})}
+}
+
+func chainNames(providers []provider) []string {
+	names := make([]string, 0, len(providers))
+	for _, p := range providers {
+		names = append(names, p.name())
+	}
+	return names
+}
+
+func flaggedNames(m *outlierMonitor) []string {
+	var names []string
+	for _, p := range m.providers {
+		if m.flagged[p.name()] {
+			names = append(names, p.name())
+		}
+	}
+	return names
+}
+
+// stubProvider reports a fixed current observation.
+type stubProvider struct {
+	n   string
+	obs *weather.Observation
+	err error
+}
+
+func (p *stubProvider) name() string {
+	return p.n
+}
+
+func (p *stubProvider) current(_ context.Context, _ weather.Location) (*weather.Observation, error) {
+	return p.obs, p.err
+}
+
+func (p *stubProvider) forecast(_ context.Context, _ weather.Location) (*weather.Forecast, error) {
+	return nil, errNotSupported
+}
+
+func (p *stubProvider) history(_ context.Context, _ weather.Location, _ time.Time) (*weather.Observation, error) {
+	return nil, errNotSupported
+}
+
+func (p *stubProvider) alerts(_ context.Context, _ weather.Location) ([]weather.Alert, error) {
+	return nil, errNotSupported
+}
+
+func (p *stubProvider) airQuality(_ context.Context, _ weather.Location) (*weather.AirQuality, error) {
+	return nil, errNotSupported
+}
+
+// hangingProvider does not respond until the request is cancelled.
+type hangingProvider struct {
+	stubProvider
+}
+
+func (p *hangingProvider) current(ctx context.Context, _ weather.Location) (*weather.Observation, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/joshjon/sydneyweather/internal/weather"
+)
+
+type ForecastResponse struct {
+	XMLName    xml.Name                 `json:"-" xml:"forecast"`
+	Provenance *ProvenanceResponse      `json:"provenance" xml:"provenance"`
+	Hourly     []HourlyForecastResponse `json:"hourly" xml:"hourly>hour"`
+	Daily      []DailyForecastResponse  `json:"daily" xml:"daily>day"`
+}
+
+type HourlyForecastResponse struct {
+	XMLName xml.Name  `json:"-" xml:"hour"`
+	Time    time.Time `json:"time" xml:"time"`
+	ExtendedWeatherResponse
+	PrecipChance *int `json:"precip_chance_percent" xml:"precip_chance_percent"`
+}
+
+type DailyForecastResponse struct {
+	Date           string     `json:"date" xml:"date"` // Local date in YYYY-MM-DD format
+	MinTempDegrees float64    `json:"min_temperature_degrees" xml:"min_temperature_degrees"`
+	MaxTempDegrees float64    `json:"max_temperature_degrees" xml:"max_temperature_degrees"`
+	Condition      string     `json:"condition" xml:"condition"`
+	Description    string     `json:"description" xml:"description"`
+	Icon           string     `json:"icon" xml:"icon"`
+	Humidity       *int       `json:"humidity_percent" xml:"humidity_percent"`
+	PrecipChance   *int       `json:"precip_chance_percent" xml:"precip_chance_percent"`
+	Precip         *float64   `json:"precip_mm" xml:"precip_mm"`
+	Sunrise        *time.Time `json:"sunrise" xml:"sunrise"`
+	Sunset         *time.Time `json:"sunset" xml:"sunset"`
+}
+
+// csvRecords returns the hourly table followed by an empty line and the daily
+// table, since their columns differ. The provenance is repeated in the leading
+// columns of every row.
+func (r *ForecastResponse) csvRecords() [][]string {
+	var records [][]string
+
+	for i, h := range r.Hourly {
+		hour := h.ExtendedWeatherResponse
+		hour.Provenance = r.Provenance
+		header, row := hour.csvFields()
+		if i == 0 {
+			records = append(records, append(append([]string{"time"}, header...), "precip_chance_percent"))
+		}
+		records = append(records, append(append([]string{h.Time.Format(time.RFC3339)}, row...), formatOptInt(h.PrecipChance)))
+	}
+
+	records = append(records, []string{})
+
+	header, provenance := r.Provenance.csvFields()
+	records = append(records, append(header,
+		"date", "min_temperature_degrees", "max_temperature_degrees", "condition", "description", "icon",
+		"humidity_percent", "precip_chance_percent", "precip_mm", "sunrise", "sunset",
+	))
+	for _, d := range r.Daily {
+		records = append(records, append(append([]string(nil), provenance...),
+			d.Date, formatFloat(d.MinTempDegrees), formatFloat(d.MaxTempDegrees), d.Condition, d.Description, d.Icon,
+			formatOptInt(d.Humidity), formatOptInt(d.PrecipChance), formatOptFloat(d.Precip),
+			formatOptTime(d.Sunrise), formatOptTime(d.Sunset),
+		))
+	}
+
+	return records
+}
+
+// summary describes the first day of the daily forecast.
+func (r *ForecastResponse) summary() string {
+	if len(r.Daily) == 0 {
+		return "No forecast"
+	}
+
+	d := r.Daily[0]
+	s := fmt.Sprintf("%s: %.1f to %.1f°C", d.Date, d.MinTempDegrees, d.MaxTempDegrees)
+	if d.Description != "" {
+		s += ", " + d.Description
+	}
+	if d.PrecipChance != nil {
+		s += fmt.Sprintf(", %d%% chance of rain", *d.PrecipChance)
+	}
+	if r.Provenance != nil {
+		s += " (" + r.Provenance.Source + ")"
+	}
+	return s
+}
+
+// GetForecast returns the hourly forecast for the next 48 hours and the daily
+// forecast for the next 7 days for the specified city. Forecasts are cached for
+// longer than current conditions but otherwise follow the same retrieval order.
+// The response is rendered in the negotiated format.
+func (s *Service) GetForecast(ctx echo.Context) error {
+	if err := validateCity(ctx); err != nil {
+		return err
+	}
+
+	f, err := negotiate(ctx)
+	if err != nil {
+		return err
+	}
+
+	res, err := fetch(ctx.Request().Context(), s.chain(), s.forecastCache, "forecast", func(c context.Context, p provider) (*weather.Forecast, error) {
+		return p.forecast(c, location)
+	})
+	if err != nil {
+		return err
+	}
+
+	resp := newForecastResponse(res.value)
+	resp.Provenance = newProvenanceResponse(res, nil)
+
+	return render(ctx, f, http.StatusOK, resp)
+}
+
+func newForecastResponse(forecast *weather.Forecast) *ForecastResponse {
+	resp := &ForecastResponse{
+		Hourly: make([]HourlyForecastResponse, 0, len(forecast.Hourly)),
+		Daily:  make([]DailyForecastResponse, 0, len(forecast.Daily)),
+	}
+
+	for _, h := range forecast.Hourly {
+		resp.Hourly = append(resp.Hourly, HourlyForecastResponse{
+			Time:                    h.Time,
+			ExtendedWeatherResponse: *newExtendedWeatherResponse(&h.Observation),
+			PrecipChance:            h.PrecipChance,
+		})
+	}
+
+	for _, d := range forecast.Daily {
+		resp.Daily = append(resp.Daily, DailyForecastResponse{
+			Date:           d.Date.Format("2006-01-02"),
+			MinTempDegrees: d.MinTemp,
+			MaxTempDegrees: d.MaxTemp,
+			Condition:      string(d.Condition),
+			Description:    d.Description,
+			Icon:           d.Condition.Icon(true),
+			Humidity:       d.Humidity,
+			PrecipChance:   d.PrecipChance,
+			Precip:         d.Precip,
+			Sunrise:        d.Sunrise,
+			Sunset:         d.Sunset,
+		})
+	}
+
+	return resp
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_GetForecast(t *testing.T) {
+	tests := []struct {
+		name            string
+		primaryEnabled  bool
+		failOverEnabled bool
+	}{
+		{
+			name:            "get forecast from primary source",
+			primaryEnabled:  true,
+			failOverEnabled: false,
+		},
+		{
+			name:            "get forecast from fail over source",
+			primaryEnabled:  false,
+			failOverEnabled: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/v2/weather/forecast?city="+wantCity, nil)
+			rec := httptest.NewRecorder()
+			ctx := e.NewContext(req, rec)
+
+			primary := &mockWeatherStackClient{wantErr: !tt.primaryEnabled}
+			failOver := &mockOpenWeatherClient{wantErr: !tt.failOverEnabled}
+			s := newTestService(primary, failOver)
+
+			err := s.GetForecast(ctx)
+			require.NoError(t, err)
+			require.Equal(t, http.StatusOK, rec.Code)
+
+			var resp ForecastResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			require.Len(t, resp.Hourly, 1)
+			require.InDelta(t, wantSpeed, resp.Hourly[0].WindSpeed, 0.001)
+			require.Equal(t, float64(wantTemp), resp.Hourly[0].TempDegrees)
+			require.Len(t, resp.Daily, 1)
+			require.Equal(t, float64(wantMinTemp), resp.Daily[0].MinTempDegrees)
+			require.Equal(t, float64(wantTemp), resp.Daily[0].MaxTempDegrees)
+		})
+	}
+}
+
+func TestService_GetForecast_unavailableError(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/v2/weather/forecast?city="+wantCity, nil)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+
+	s := newTestService(&mockWeatherStackClient{wantErr: true}, &mockOpenWeatherClient{wantErr: true})
+
+	err := s.GetForecast(ctx)
+	require.EqualError(t, err, "code=503, message=Service Unavailable, internal=no provider could return forecast")
+}
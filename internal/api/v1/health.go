@@ -0,0 +1,389 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/joshjon/sydneyweather/internal/weather"
+)
+
+// Health defaults used when HealthConfig values are not set.
+const (
+	defaultFailureThreshold = 5
+	defaultCircuitCooldown  = 30 * time.Second
+	latencyWindow           = 100
+)
+
+// Circuit states. Each provider has a circuit per operation, which opens after
+// consecutive failures of that operation and skips the provider in its chain
+// until the cooldown has passed. The circuit is then half open and the next
+// call decides whether it closes or opens again.
+const (
+	circuitClosed   = "closed"
+	circuitOpen     = "open"
+	circuitHalfOpen = "half-open"
+)
+
+// Overall statuses reported by the status and readiness endpoints.
+const (
+	statusOK          = "ok"
+	statusDegraded    = "degraded"
+	statusUnavailable = "unavailable"
+)
+
+// Error classes of provider failures.
+const (
+	errorClassImplausible  = "implausible"
+	errorClassTimeout      = "timeout"
+	errorClassCanceled     = "canceled"
+	errorClassRateLimited  = "rate_limited"
+	errorClassUnauthorized = "unauthorized"
+	errorClassClient       = "client_error"
+	errorClassServer       = "server_error"
+	errorClassNetwork      = "network"
+	errorClassUnknown      = "unknown"
+)
+
+// operationCurrent is the operation label of current conditions, whose
+// circuits decide readiness.
+const operationCurrent = "weather"
+
+// errCircuitOpen is the chain failure of a provider skipped by its circuit.
+var errCircuitOpen = errors.New("circuit open")
+
+// HealthConfig configures provider circuits and readiness. A provider is down
+// while its current conditions circuit is open. By default the service is not ready when every
+// provider is down. With ReadyWhileStale it stays ready as long as the current
+// conditions cache holds a value no older than MaxStaleAge (0 is no limit),
+// since requests are then answered with stale data.
+type HealthConfig struct {
+	FailureThreshold int
+	Cooldown         time.Duration
+	ReadyWhileStale  bool
+	MaxStaleAge      time.Duration
+}
+
+type HealthResponse struct {
+	Status string `json:"status"`
+}
+
+type StatusResponse struct {
+	Status    string                   `json:"status"`
+	Providers []ProviderStatusResponse `json:"providers"`
+	Caches    []CacheStatusResponse    `json:"caches"`
+}
+
+// ProviderStatusResponse describes a provider's current conditions calls, with
+// the circuit of every operation it has been called for.
+type ProviderStatusResponse struct {
+	Name                string            `json:"name"`
+	Circuit             string            `json:"circuit"`
+	Circuits            map[string]string `json:"circuits"`
+	ConsecutiveFailures int               `json:"consecutive_failures"`
+	LastSuccess         *time.Time        `json:"last_success"`
+	LastError           *time.Time        `json:"last_error"`
+	LastErrorClass      *string           `json:"last_error_class"`
+	Latency             *LatencyResponse  `json:"latency_ms"`
+}
+
+// LatencyResponse holds latency percentiles in milliseconds over the most
+// recent provider calls.
+type LatencyResponse struct {
+	Samples int     `json:"samples"`
+	P50     float64 `json:"p50"`
+	P90     float64 `json:"p90"`
+	P99     float64 `json:"p99"`
+}
+
+// CacheStatusResponse describes the value held by a cache. Fields are null when
+// the cache is empty.
+type CacheStatusResponse struct {
+	Name       string     `json:"name"`
+	Fresh      bool       `json:"fresh"`
+	Source     *string    `json:"source"`
+	FetchedAt  *time.Time `json:"fetched_at"`
+	AgeSeconds *int       `json:"age_seconds"`
+}
+
+// GetHealth reports that the service is alive. It does not depend on providers
+// so that orchestrators do not restart the service during a provider outage.
+func (s *Service) GetHealth(ctx echo.Context) error {
+	return ctx.JSON(http.StatusOK, &HealthResponse{Status: statusOK})
+}
+
+// GetReadiness reports whether the service can answer requests, responding with
+// 503 Service Unavailable when it cannot.
+func (s *Service) GetReadiness(ctx echo.Context) error {
+	status := s.status()
+	code := http.StatusOK
+	if status == statusUnavailable {
+		code = http.StatusServiceUnavailable
+	}
+	return ctx.JSON(code, &HealthResponse{Status: status})
+}
+
+// GetStatus reports the health of each provider and the freshness of each
+// cache.
+func (s *Service) GetStatus(ctx echo.Context) error {
+	resp := &StatusResponse{
+		Status:    s.status(),
+		Providers: s.health.statuses(s.providers, time.Now()),
+		Caches: []CacheStatusResponse{
+			newCacheStatusResponse("weather", s.obsCache),
+			newCacheStatusResponse("forecast", s.forecastCache),
+			newCacheStatusResponse("alerts", s.alertsCache),
+			newCacheStatusResponse("air_quality", s.airCache),
+		},
+	}
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// status returns ok when every provider is up, degraded when some are down and
+// unavailable when all are down, unless usable stale data is allowed to keep
+// the service ready.
+func (s *Service) status() string {
+	down := 0
+	now := time.Now()
+	for _, p := range s.providers {
+		if s.health.circuit(p.name(), operationCurrent, now) == circuitOpen {
+			down++
+		}
+	}
+
+	switch {
+	case down == 0:
+		return statusOK
+	case down < len(s.providers):
+		return statusDegraded
+	case s.health.cfg.ReadyWhileStale && s.usableStaleData():
+		return statusDegraded
+	default:
+		return statusUnavailable
+	}
+}
+
+func (s *Service) usableStaleData() bool {
+	res, ok := s.obsCache.get()
+	if !ok {
+		return false
+	}
+	return s.health.cfg.MaxStaleAge <= 0 || res.age() <= s.health.cfg.MaxStaleAge
+}
+
+func newCacheStatusResponse[T any](name string, cache *valueCache[result[T]]) CacheStatusResponse {
+	resp := CacheStatusResponse{Name: name}
+	res, ok := cache.get()
+	if !ok {
+		return resp
+	}
+
+	fetchedAt, age := res.fetchedAt.UTC(), int(res.age().Seconds())
+	resp.Fresh = !cache.expired()
+	resp.Source, resp.FetchedAt, resp.AgeSeconds = &res.source, &fetchedAt, &age
+	return resp
+}
+
+// healthTracker records the outcome and latency of provider calls made by the
+// chain and decides whether each provider's circuit for an operation is open.
+// Operations have separate circuits so that failures of one, e.g. history,
+// do not stop the provider being used for current conditions.
+type healthTracker struct {
+	cfg      HealthConfig
+	mu       sync.RWMutex
+	circuits map[circuitKey]*providerHealth
+}
+
+type circuitKey struct {
+	provider  string
+	operation string
+}
+
+type providerHealth struct {
+	consecutiveFailures int
+	lastSuccess         time.Time
+	lastError           time.Time
+	lastErrorClass      string
+	latencies           []time.Duration // Ring buffer of the most recent calls
+	next                int
+}
+
+func newHealthTracker(cfg HealthConfig) *healthTracker {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = defaultFailureThreshold
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = defaultCircuitCooldown
+	}
+	return &healthTracker{
+		cfg:      cfg,
+		circuits: make(map[circuitKey]*providerHealth),
+	}
+}
+
+// allow reports whether the provider's circuit for the operation lets a call
+// through.
+func (h *healthTracker) allow(p provider, op string) bool {
+	return h.circuit(p.name(), op, time.Now()) != circuitOpen
+}
+
+// record records the outcome of a provider call for the operation made at now.
+// Calls canceled by the caller, e.g. because the client went away, say nothing
+// about the provider and are ignored. Requests for history the provider does
+// not have or data its plan does not include were answered, so only their
+// latency is recorded.
+func (h *healthTracker) record(p provider, op string, err error, duration time.Duration, now time.Time) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := circuitKey{provider: p.name(), operation: op}
+	ph, ok := h.circuits[key]
+	if !ok {
+		ph = &providerHealth{latencies: make([]time.Duration, 0, latencyWindow)}
+		h.circuits[key] = ph
+	}
+
+	if len(ph.latencies) < latencyWindow {
+		ph.latencies = append(ph.latencies, duration)
+	} else {
+		ph.latencies[ph.next] = duration
+	}
+	ph.next = (ph.next + 1) % latencyWindow
+
+	if errors.Is(err, weather.ErrHistoryNotFound) || errors.Is(err, errPlanNotSupported) {
+		return
+	}
+
+	if err == nil {
+		ph.consecutiveFailures = 0
+		ph.lastSuccess = now
+		return
+	}
+
+	ph.consecutiveFailures++
+	ph.lastError = now
+	ph.lastErrorClass = errorClass(err)
+}
+
+func (h *healthTracker) circuit(name string, op string, now time.Time) string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.circuitLocked(h.circuits[circuitKey{provider: name, operation: op}], now)
+}
+
+func (h *healthTracker) circuitLocked(ph *providerHealth, now time.Time) string {
+	if ph == nil || ph.consecutiveFailures < h.cfg.FailureThreshold {
+		return circuitClosed
+	}
+	if now.Sub(ph.lastError) < h.cfg.Cooldown {
+		return circuitOpen
+	}
+	return circuitHalfOpen
+}
+
+// statuses returns the health of each provider in configured order.
+func (h *healthTracker) statuses(providers []provider, now time.Time) []ProviderStatusResponse {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	resp := make([]ProviderStatusResponse, 0, len(providers))
+	for _, p := range providers {
+		ph := h.circuits[circuitKey{provider: p.name(), operation: operationCurrent}]
+		status := ProviderStatusResponse{
+			Name:     p.name(),
+			Circuit:  h.circuitLocked(ph, now),
+			Circuits: map[string]string{operationCurrent: h.circuitLocked(ph, now)},
+		}
+		for key, oph := range h.circuits {
+			if key.provider == p.name() {
+				status.Circuits[key.operation] = h.circuitLocked(oph, now)
+			}
+		}
+		if ph != nil {
+			status.ConsecutiveFailures = ph.consecutiveFailures
+			status.Latency = newLatencyResponse(ph.latencies)
+			if !ph.lastSuccess.IsZero() {
+				lastSuccess := ph.lastSuccess.UTC()
+				status.LastSuccess = &lastSuccess
+			}
+			if !ph.lastError.IsZero() {
+				lastError, class := ph.lastError.UTC(), ph.lastErrorClass
+				status.LastError, status.LastErrorClass = &lastError, &class
+			}
+		}
+		resp = append(resp, status)
+	}
+
+	return resp
+}
+
+func newLatencyResponse(latencies []time.Duration) *LatencyResponse {
+	if len(latencies) == 0 {
+		return nil
+	}
+
+	sorted := make([]time.Duration, len(latencies))
+	copy(sorted, latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return &LatencyResponse{
+		Samples: len(sorted),
+		P50:     percentile(sorted, 50),
+		P90:     percentile(sorted, 90),
+		P99:     percentile(sorted, 99),
+	}
+}
+
+// percentile returns the nearest rank percentile of sorted latencies in
+// milliseconds.
+func percentile(sorted []time.Duration, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return float64(sorted[rank-1].Microseconds()) / 1000
+}
+
+// errorClass classifies a provider failure without exposing its message.
+func errorClass(err error) string {
+	var httpErr *weather.HTTPError
+	var netErr net.Error
+
+	switch {
+	case errors.Is(err, weather.ErrImplausibleObservation):
+		return errorClassImplausible
+	case errors.Is(err, context.DeadlineExceeded):
+		return errorClassTimeout
+	case errors.Is(err, context.Canceled):
+		return errorClassCanceled
+	case errors.As(err, &httpErr):
+		switch {
+		case httpErr.StatusCode == http.StatusTooManyRequests:
+			return errorClassRateLimited
+		case httpErr.StatusCode == http.StatusUnauthorized || httpErr.StatusCode == http.StatusForbidden:
+			return errorClassUnauthorized
+		case httpErr.StatusCode >= http.StatusInternalServerError:
+			return errorClassServer
+		default:
+			return errorClassClient
+		}
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return errorClassTimeout
+		}
+		return errorClassNetwork
+	default:
+		return errorClassUnknown
+	}
+}
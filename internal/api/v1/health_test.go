@@ -0,0 +1,287 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshjon/sydneyweather/internal/weather"
+)
+
+func TestHealthTracker_circuit(t *testing.T) {
+	h := newHealthTracker(HealthConfig{FailureThreshold: 2, Cooldown: time.Minute})
+	p := &stubProvider{n: "some-provider"}
+	now := time.Now()
+
+	require.Equal(t, circuitClosed, h.circuit(p.name(), operationCurrent, now))
+
+	h.record(p, operationCurrent, errors.New("some-error"), time.Millisecond, now)
+	require.Equal(t, circuitClosed, h.circuit(p.name(), operationCurrent, now))
+
+	h.record(p, operationCurrent, errors.New("some-error"), time.Millisecond, now)
+	require.Equal(t, circuitOpen, h.circuit(p.name(), operationCurrent, now))
+	require.Equal(t, circuitHalfOpen, h.circuit(p.name(), operationCurrent, now.Add(time.Minute)))
+
+	// A failure while half open opens the circuit again
+	h.record(p, operationCurrent, errors.New("some-error"), time.Millisecond, now.Add(time.Minute))
+	require.Equal(t, circuitOpen, h.circuit(p.name(), operationCurrent, now.Add(time.Minute)))
+
+	h.record(p, operationCurrent, nil, time.Millisecond, now.Add(2*time.Minute))
+	require.Equal(t, circuitClosed, h.circuit(p.name(), operationCurrent, now.Add(2*time.Minute)))
+}
+
+func TestHealthTracker_record_canceled(t *testing.T) {
+	h := newHealthTracker(HealthConfig{FailureThreshold: 1, Cooldown: time.Minute})
+	p := &stubProvider{n: "some-provider"}
+	now := time.Now()
+
+	h.record(p, operationCurrent, fmt.Errorf("some-error: %w", context.Canceled), time.Millisecond, now)
+	require.Equal(t, circuitClosed, h.circuit(p.name(), operationCurrent, now))
+	require.Empty(t, h.circuits)
+
+	h.record(p, operationCurrent, errors.New("some-error"), time.Millisecond, now)
+	require.Equal(t, circuitOpen, h.circuit(p.name(), operationCurrent, now))
+}
+
+func TestHealthTracker_record_operations(t *testing.T) {
+	h := newHealthTracker(HealthConfig{FailureThreshold: 1, Cooldown: time.Minute})
+	p := &stubProvider{n: "some-provider"}
+	now := time.Now()
+
+	h.record(p, "history", fmt.Errorf("some-error: %w", weather.ErrHistoryNotFound), time.Millisecond, now)
+	h.record(p, "alerts", fmt.Errorf("%w: %w", errPlanNotSupported, &weather.HTTPError{StatusCode: http.StatusUnauthorized}), time.Millisecond, now)
+	require.Equal(t, circuitClosed, h.circuit(p.name(), "history", now))
+	require.Equal(t, circuitClosed, h.circuit(p.name(), "alerts", now))
+
+	h.record(p, "forecast", errors.New("some-error"), time.Millisecond, now)
+	require.Equal(t, circuitOpen, h.circuit(p.name(), "forecast", now))
+	require.Equal(t, circuitClosed, h.circuit(p.name(), operationCurrent, now))
+}
+
+func TestPlanError(t *testing.T) {
+	tests := []struct {
+		err      error
+		wantPlan bool
+	}{
+		{err: &weather.HTTPError{StatusCode: http.StatusUnauthorized}, wantPlan: true},
+		{err: &weather.HTTPError{StatusCode: http.StatusForbidden}, wantPlan: true},
+		{err: &weather.HTTPError{StatusCode: http.StatusTooManyRequests}},
+		{err: errors.New("some-error")},
+	}
+
+	for _, tt := range tests {
+		err := planError(tt.err)
+		require.ErrorIs(t, err, tt.err)
+		require.Equal(t, tt.wantPlan, errors.Is(err, errPlanNotSupported))
+	}
+}
+
+func TestQuery_circuitOpen(t *testing.T) {
+	h := newHealthTracker(HealthConfig{FailureThreshold: 1, Cooldown: time.Minute})
+	providers := []provider{
+		&stubProvider{n: "a", err: errors.New("some-error")},
+		&stubProvider{n: "b", err: errors.New("some-error")},
+	}
+	get := func(ctx context.Context, p provider) (*weather.Observation, error) {
+		return p.current(ctx, location)
+	}
+
+	_, err := query(context.Background(), &providerChain{providers: providers, metrics: newMetrics(), health: h}, "weather", get)
+	var chainErr *chainError
+	require.ErrorAs(t, err, &chainErr)
+	require.Equal(t, []ProviderFailure{{Provider: "a", Error: "some-error"}, {Provider: "b", Error: "some-error"}}, chainErr.failures)
+
+	_, err = query(context.Background(), &providerChain{providers: providers, metrics: newMetrics(), health: h}, "weather", get)
+	require.ErrorAs(t, err, &chainErr)
+	require.Equal(t, []ProviderFailure{{Provider: "a", Error: "circuit open"}, {Provider: "b", Error: "circuit open"}}, chainErr.failures)
+}
+
+func TestService_GetReadiness(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        HealthConfig
+		down       []string
+		operation  string
+		cacheAge   *time.Duration
+		wantCode   int
+		wantStatus string
+	}{
+		{
+			name:       "all providers up",
+			wantCode:   http.StatusOK,
+			wantStatus: statusOK,
+		},
+		{
+			name:       "some providers down",
+			down:       []string{"weatherstack"},
+			wantCode:   http.StatusOK,
+			wantStatus: statusDegraded,
+		},
+		{
+			name:       "all providers failing other operations",
+			down:       []string{"weatherstack", "openweather"},
+			operation:  "forecast",
+			wantCode:   http.StatusOK,
+			wantStatus: statusOK,
+		},
+		{
+			name:       "all providers down",
+			down:       []string{"weatherstack", "openweather"},
+			cacheAge:   ptr(time.Minute),
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: statusUnavailable,
+		},
+		{
+			name:       "all providers down with stale data",
+			cfg:        HealthConfig{ReadyWhileStale: true, MaxStaleAge: time.Hour},
+			down:       []string{"weatherstack", "openweather"},
+			cacheAge:   ptr(time.Minute),
+			wantCode:   http.StatusOK,
+			wantStatus: statusDegraded,
+		},
+		{
+			name:       "all providers down with stale data too old",
+			cfg:        HealthConfig{ReadyWhileStale: true, MaxStaleAge: time.Hour},
+			down:       []string{"weatherstack", "openweather"},
+			cacheAge:   ptr(2 * time.Hour),
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: statusUnavailable,
+		},
+		{
+			name:       "all providers down without stale data",
+			cfg:        HealthConfig{ReadyWhileStale: true},
+			down:       []string{"weatherstack", "openweather"},
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: statusUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(&mockWeatherStackClient{}, &mockOpenWeatherClient{})
+			s.health = newHealthTracker(tt.cfg)
+			op := operationCurrent
+			if tt.operation != "" {
+				op = tt.operation
+			}
+			for _, name := range tt.down {
+				for i := 0; i < s.health.cfg.FailureThreshold; i++ {
+					s.health.record(&stubProvider{n: name}, op, errors.New("some-error"), time.Millisecond, time.Now())
+				}
+			}
+			if tt.cacheAge != nil {
+				s.obsCache.put(&result[weather.Observation]{
+					value:     &weather.Observation{},
+					source:    "weatherstack",
+					fetchedAt: time.Now().Add(-*tt.cacheAge),
+				})
+			}
+
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			ctx := e.NewContext(httptest.NewRequest(http.MethodGet, "/readyz", nil), rec)
+
+			require.NoError(t, s.GetReadiness(ctx))
+			require.Equal(t, tt.wantCode, rec.Code)
+
+			var resp HealthResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			require.Equal(t, tt.wantStatus, resp.Status)
+		})
+	}
+}
+
+func TestService_GetHealth(t *testing.T) {
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(httptest.NewRequest(http.MethodGet, "/healthz", nil), rec)
+
+	s := newTestService(&mockWeatherStackClient{wantErr: true}, &mockOpenWeatherClient{wantErr: true})
+	require.NoError(t, s.GetHealth(ctx))
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestService_GetStatus(t *testing.T) {
+	s := newTestService(&mockWeatherStackClient{wantErr: true}, &mockOpenWeatherClient{})
+	_, err := s.currentObservation(context.Background())
+	require.NoError(t, err)
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(httptest.NewRequest(http.MethodGet, "/v1/status", nil), rec)
+
+	require.NoError(t, s.GetStatus(ctx))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp StatusResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, statusOK, resp.Status)
+
+	require.Len(t, resp.Providers, 2)
+	weatherStack, openWeather := resp.Providers[0], resp.Providers[1]
+
+	require.Equal(t, "weatherstack", weatherStack.Name)
+	require.Equal(t, circuitClosed, weatherStack.Circuit)
+	require.Equal(t, map[string]string{operationCurrent: circuitClosed}, weatherStack.Circuits)
+	require.Equal(t, 1, weatherStack.ConsecutiveFailures)
+	require.Nil(t, weatherStack.LastSuccess)
+	require.NotNil(t, weatherStack.LastError)
+	require.Equal(t, errorClassUnknown, *weatherStack.LastErrorClass)
+	require.Equal(t, 1, weatherStack.Latency.Samples)
+
+	require.Equal(t, "openweather", openWeather.Name)
+	require.Equal(t, 0, openWeather.ConsecutiveFailures)
+	require.NotNil(t, openWeather.LastSuccess)
+	require.Nil(t, openWeather.LastError)
+	require.Nil(t, openWeather.LastErrorClass)
+
+	require.Len(t, resp.Caches, 4)
+	require.Equal(t, "weather", resp.Caches[0].Name)
+	require.True(t, resp.Caches[0].Fresh)
+	require.Equal(t, "openweather", *resp.Caches[0].Source)
+	require.NotNil(t, resp.Caches[0].AgeSeconds)
+	require.Equal(t, CacheStatusResponse{Name: "forecast"}, resp.Caches[1])
+}
+
+func TestNewLatencyResponse(t *testing.T) {
+	require.Nil(t, newLatencyResponse(nil))
+
+	var latencies []time.Duration
+	for i := 100; i > 0; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	require.Equal(t, &LatencyResponse{Samples: 100, P50: 50, P90: 90, P99: 99}, newLatencyResponse(latencies))
+}
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{err: fmt.Errorf("some-error: %w", weather.ErrImplausibleObservation), want: errorClassImplausible},
+		{err: context.DeadlineExceeded, want: errorClassTimeout},
+		{err: context.Canceled, want: errorClassCanceled},
+		{err: &weather.HTTPError{StatusCode: http.StatusTooManyRequests}, want: errorClassRateLimited},
+		{err: &weather.HTTPError{StatusCode: http.StatusUnauthorized}, want: errorClassUnauthorized},
+		{err: &weather.HTTPError{StatusCode: http.StatusNotFound}, want: errorClassClient},
+		{err: &weather.HTTPError{StatusCode: http.StatusBadGateway}, want: errorClassServer},
+		{err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, want: errorClassNetwork},
+		{err: errors.New("some-error"), want: errorClassUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			require.Equal(t, tt.want, errorClass(tt.err))
+		})
+	}
+}
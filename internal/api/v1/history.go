@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/joshjon/sydneyweather/internal/weather"
+)
+
+const (
+	dateLayout = "2006-01-02"
+
+	// earliestHistoryDate is the earliest date any provider has history for.
+	// OpenWeather has history from 1979 and weatherstack from July 2008.
+	earliestHistoryDate = "1979-01-01"
+
+	// historyCacheSize is how many dates of history are cached. About a year of
+	// observations are held before the least recently requested is evicted.
+	historyCacheSize = 400
+)
+
+type HistoryResponse struct {
+	XMLName xml.Name  `json:"-" xml:"history"`
+	Date    string    `json:"date" xml:"date"` // Local date in YYYY-MM-DD format
+	Time    time.Time `json:"time" xml:"time"`
+	ExtendedWeatherResponse
+}
+
+func (r *HistoryResponse) csvRecords() [][]string {
+	header, row := r.ExtendedWeatherResponse.csvFields()
+	return [][]string{
+		append([]string{"date", "time"}, header...),
+		append([]string{r.Date, r.Time.Format(time.RFC3339)}, row...),
+	}
+}
+
+func (r *HistoryResponse) summary() string {
+	return r.Date + ": " + r.ExtendedWeatherResponse.summary()
+}
+
+// GetHistory returns the observed conditions at local midday on the specified
+// date for the specified city. The date must be in the past and no earlier than
+// the providers have history for. Historical observations never change, so
+// they are cached until evicted by requests for other dates. The response is
+// rendered in the negotiated format.
+func (s *Service) GetHistory(ctx echo.Context) error {
+	if err := validateCity(ctx); err != nil {
+		return err
+	}
+
+	f, err := negotiate(ctx)
+	if err != nil {
+		return err
+	}
+
+	date, err := time.ParseInLocation(dateLayout, ctx.QueryParam("date"), location.Zone)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "query param 'date' must be in YYYY-MM-DD format")
+	}
+
+	now := time.Now().In(location.Zone)
+	if !date.Before(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location.Zone)) {
+		return echo.NewHTTPError(http.StatusBadRequest, "query param 'date' must be in the past")
+	}
+
+	key := date.Format(dateLayout)
+	if key < earliestHistoryDate {
+		return echo.NewHTTPError(http.StatusBadRequest, "query param 'date' must not be before "+earliestHistoryDate)
+	}
+	midday := localMidday(date)
+
+	res, err := s.historicalObservation(ctx.Request().Context(), key, midday)
+	if err != nil {
+		return err
+	}
+
+	resp := &HistoryResponse{
+		Date:                    key,
+		Time:                    midday,
+		ExtendedWeatherResponse: *newExtendedWeatherResponse(res.value),
+	}
+	resp.Provenance = newProvenanceResponse(res, res.value.ObservedAt)
+
+	return render(ctx, f, http.StatusOK, resp)
+}
+
+// localMidday returns midday in the date's location. Adding 12 hours to local
+// midnight lands an hour out on daylight saving changeover days.
+func localMidday(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 12, 0, 0, 0, date.Location())
+}
+
+// historicalObservation returns the observation at t from the history cache,
+// otherwise from the first provider in the chain to succeed.
+func (s *Service) historicalObservation(ctx context.Context, key string, t time.Time) (*result[weather.Observation], error) {
+	ctx, span := startCacheSpan(ctx, "history")
+	defer span.End()
+
+	if res, ok := s.historyCache.get(key); ok {
+		s.metrics.observeCache("history", cacheHit)
+		setCacheResult(span, cacheHit)
+		return res, nil
+	}
+
+	s.metrics.observeCache("history", cacheMiss)
+	setCacheResult(span, cacheMiss)
+
+	res, err := query(ctx, s.chain(), "history", func(ctx context.Context, p provider) (*weather.Observation, error) {
+		return s.validate(p.history(ctx, location, t))
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.historyCache.put(key, res)
+
+	return res, nil
+}
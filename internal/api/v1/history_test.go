@@ -0,0 +1,134 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_GetHistory(t *testing.T) {
+	tests := []struct {
+		name            string
+		primaryEnabled  bool
+		failOverEnabled bool
+	}{
+		{
+			name:            "get history from primary source",
+			primaryEnabled:  true,
+			failOverEnabled: false,
+		},
+		{
+			name:            "get history from fail over source",
+			primaryEnabled:  false,
+			failOverEnabled: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/v2/weather/history?city="+wantCity+"&date=2022-05-01", nil)
+			rec := httptest.NewRecorder()
+			ctx := e.NewContext(req, rec)
+
+			primary := &mockWeatherStackClient{wantErr: !tt.primaryEnabled}
+			failOver := &mockOpenWeatherClient{wantErr: !tt.failOverEnabled}
+			s := newTestService(primary, failOver)
+
+			err := s.GetHistory(ctx)
+			require.NoError(t, err)
+			require.Equal(t, http.StatusOK, rec.Code)
+
+			var resp HistoryResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			require.Equal(t, "2022-05-01", resp.Date)
+			require.True(t, resp.Time.Equal(time.Date(2022, 5, 1, 12, 0, 0, 0, location.Zone)))
+			require.InDelta(t, wantSpeed, resp.WindSpeed, 0.001)
+			require.Equal(t, float64(wantTemp), resp.TempDegrees)
+		})
+	}
+}
+
+func TestService_GetHistory_useCache(t *testing.T) {
+	e := echo.New()
+	s := newTestService(&mockWeatherStackClient{}, &mockOpenWeatherClient{})
+
+	req := httptest.NewRequest(http.MethodGet, "/v2/weather/history?city="+wantCity+"&date=2022-05-01", nil)
+	rec := httptest.NewRecorder()
+	require.NoError(t, s.GetHistory(e.NewContext(req, rec)))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	// Historical observations are cached without expiring
+	failing := newTestService(&mockWeatherStackClient{wantErr: true}, &mockOpenWeatherClient{wantErr: true})
+	s.providers, s.outliers = failing.providers, failing.outliers
+	rec = httptest.NewRecorder()
+	require.NoError(t, s.GetHistory(e.NewContext(req, rec)))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp HistoryResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, float64(wantTemp), resp.TempDegrees)
+}
+
+func TestService_GetHistory_invalidDate(t *testing.T) {
+	tests := []struct {
+		name    string
+		date    string
+		wantErr string
+	}{
+		{
+			name:    "invalid format",
+			date:    "01-05-2022",
+			wantErr: "code=400, message=query param 'date' must be in YYYY-MM-DD format",
+		},
+		{
+			name:    "today",
+			date:    time.Now().In(location.Zone).Format(dateLayout),
+			wantErr: "code=400, message=query param 'date' must be in the past",
+		},
+		{
+			name:    "before history",
+			date:    "1978-12-31",
+			wantErr: "code=400, message=query param 'date' must not be before 1979-01-01",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/v2/weather/history?city="+wantCity+"&date="+tt.date, nil)
+			ctx := e.NewContext(req, httptest.NewRecorder())
+
+			s := newTestService(&mockWeatherStackClient{}, &mockOpenWeatherClient{})
+			require.EqualError(t, s.GetHistory(ctx), tt.wantErr)
+		})
+	}
+}
+
+func TestLocalMidday(t *testing.T) {
+	tests := []struct {
+		name string
+		date string
+	}{
+		{name: "standard time", date: "2022-06-01"},
+		{name: "daylight saving ends", date: "2022-04-03"},
+		{name: "daylight saving starts", date: "2022-10-02"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			date, err := time.ParseInLocation(dateLayout, tt.date, location.Zone)
+			require.NoError(t, err)
+
+			midday := localMidday(date)
+			require.Equal(t, tt.date, midday.Format(dateLayout))
+			require.Equal(t, 12, midday.Hour())
+			require.Equal(t, 0, midday.Minute())
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HTTP caching headers that echo does not define.
+const (
+	headerETag        = "ETag"
+	headerAge         = "Age"
+	headerIfNoneMatch = "If-None-Match"
+)
+
+// setCacheHeaders sets HTTP caching headers derived from the cache entry of the
+// result, so that CDNs and browsers can cache the response until the entry
+// expires. The ETag identifies the entry, which changes whenever a provider is
+// queried, and the format it is rendered in. Stale results must be revalidated
+// since a fresh one could be fetched at any time. Authenticated responses are
+// made private by the auth middleware.
+func setCacheHeaders[T any](ctx echo.Context, r *result[T], expiry time.Duration, f format) {
+	h := ctx.Response().Header()
+	h.Set(echo.HeaderLastModified, r.fetchedAt.UTC().Format(http.TimeFormat))
+	h.Set(headerETag, fmt.Sprintf(`"%s-%x-%s"`, r.source, r.fetchedAt.UnixNano(), f))
+	h.Set(headerAge, strconv.Itoa(int(r.age().Seconds())))
+
+	if r.stale {
+		h.Set(echo.HeaderCacheControl, "no-cache")
+	} else {
+		h.Set(echo.HeaderCacheControl, fmt.Sprintf("public, max-age=%d", int(expiry.Seconds())))
+	}
+}
+
+// notModified returns true if the conditional request headers show the client
+// already has the representation described by the ETag and Last-Modified
+// response headers. If-None-Match takes precedence over If-Modified-Since as per
+// RFC 7232.
+func notModified(ctx echo.Context) bool {
+	req, h := ctx.Request(), ctx.Response().Header()
+
+	if inm := req.Header.Get(headerIfNoneMatch); inm != "" {
+		etag := h.Get(headerETag)
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	ims, err := http.ParseTime(req.Header.Get(echo.HeaderIfModifiedSince))
+	if err != nil {
+		return false
+	}
+	lastModified, err := http.ParseTime(h.Get(echo.HeaderLastModified))
+	if err != nil {
+		return false
+	}
+
+	return !lastModified.After(ims)
+}
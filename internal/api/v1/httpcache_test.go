@@ -0,0 +1,135 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshjon/sydneyweather/internal/weather"
+)
+
+func TestService_GetWeather_cacheHeaders(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/v1/weather?city="+wantCity, nil)
+	rec := httptest.NewRecorder()
+
+	s := newTestService(&mockWeatherStackClient{}, &mockOpenWeatherClient{})
+	require.NoError(t, s.GetWeather(e.NewContext(req, rec)))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	res, ok := s.obsCache.get()
+	require.True(t, ok)
+
+	h := rec.Header()
+	require.Equal(t, "public, max-age=0", h.Get(echo.HeaderCacheControl)) // Test cache expiry is under a second
+	require.Equal(t, "0", h.Get(headerAge))
+	require.Equal(t, res.fetchedAt.UTC().Format(http.TimeFormat), h.Get(echo.HeaderLastModified))
+	require.Contains(t, h.Get(headerETag), `"weatherstack-`)
+}
+
+func TestService_GetWeather_conditional(t *testing.T) {
+	e := echo.New()
+	s := newTestService(&mockWeatherStackClient{}, &mockOpenWeatherClient{})
+	s.obsCache = newValueCache[result[weather.Observation]](time.Minute) // The ETag must not change between requests
+
+	rec := httptest.NewRecorder()
+	require.NoError(t, s.GetWeather(e.NewContext(httptest.NewRequest(http.MethodGet, "/v1/weather?city="+wantCity, nil), rec)))
+	etag := rec.Header().Get(headerETag)
+	lastModified, err := http.ParseTime(rec.Header().Get(echo.HeaderLastModified))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name            string
+		ifNoneMatch     string
+		ifModifiedSince string
+		wantCode        int
+	}{
+		{
+			name:     "unconditional",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:        "etag matches",
+			ifNoneMatch: etag,
+			wantCode:    http.StatusNotModified,
+		},
+		{
+			name:        "weak etag in list matches",
+			ifNoneMatch: `"some-etag", W/` + etag,
+			wantCode:    http.StatusNotModified,
+		},
+		{
+			name:        "wildcard",
+			ifNoneMatch: "*",
+			wantCode:    http.StatusNotModified,
+		},
+		{
+			name:        "etag does not match",
+			ifNoneMatch: `"some-etag"`,
+			wantCode:    http.StatusOK,
+		},
+		{
+			name:            "not modified since",
+			ifModifiedSince: lastModified.Format(http.TimeFormat),
+			wantCode:        http.StatusNotModified,
+		},
+		{
+			name:            "modified since",
+			ifModifiedSince: lastModified.Add(-time.Second).Format(http.TimeFormat),
+			wantCode:        http.StatusOK,
+		},
+		{
+			name:            "etag takes precedence over date",
+			ifNoneMatch:     `"some-etag"`,
+			ifModifiedSince: lastModified.Format(http.TimeFormat),
+			wantCode:        http.StatusOK,
+		},
+		{
+			name:            "invalid date",
+			ifModifiedSince: "some-date",
+			wantCode:        http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/weather?city="+wantCity, nil)
+			if tt.ifNoneMatch != "" {
+				req.Header.Set(headerIfNoneMatch, tt.ifNoneMatch)
+			}
+			if tt.ifModifiedSince != "" {
+				req.Header.Set(echo.HeaderIfModifiedSince, tt.ifModifiedSince)
+			}
+			rec := httptest.NewRecorder()
+
+			require.NoError(t, s.GetWeather(e.NewContext(req, rec)))
+			require.Equal(t, tt.wantCode, rec.Code)
+			require.Equal(t, etag, rec.Header().Get(headerETag))
+			require.Equal(t, "public, max-age=60", rec.Header().Get(echo.HeaderCacheControl))
+			require.Equal(t, echo.HeaderAccept, rec.Header().Get(echo.HeaderVary))
+			if tt.wantCode == http.StatusNotModified {
+				require.Empty(t, rec.Body.Bytes())
+			}
+		})
+	}
+}
+
+func TestService_GetWeather_cacheHeadersStale(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/v1/weather?city="+wantCity, nil)
+	s := newTestService(&mockWeatherStackClient{}, &mockOpenWeatherClient{})
+	require.NoError(t, s.GetWeather(e.NewContext(req, httptest.NewRecorder())))
+
+	time.Sleep(100*time.Millisecond + time.Millisecond)
+	failing := newTestService(&mockWeatherStackClient{wantErr: true}, &mockOpenWeatherClient{wantErr: true})
+	s.providers, s.outliers = failing.providers, failing.outliers
+
+	rec := httptest.NewRecorder()
+	require.NoError(t, s.GetWeather(e.NewContext(req, rec)))
+	require.Equal(t, "true", rec.Header().Get(headerStale))
+	require.Equal(t, "no-cache", rec.Header().Get(echo.HeaderCacheControl))
+}
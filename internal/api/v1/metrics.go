@@ -0,0 +1,155 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/joshjon/sydneyweather/internal/weather"
+)
+
+const metricsNamespace = "sydneyweather"
+
+// Cache lookup results.
+const (
+	cacheHit   = "hit"
+	cacheMiss  = "miss"
+	cacheStale = "stale"
+)
+
+// Provider call outcomes. Implausible observations are distinguished from
+// errors since the provider answered but could not be trusted.
+const (
+	outcomeSuccess     = "success"
+	outcomeError       = "error"
+	outcomeImplausible = "implausible"
+)
+
+// metrics are the Prometheus metrics of a service. Each service has its own
+// registry so that services can be created more than once, e.g. in tests.
+// Request and provider call counts are the '_count' series of the histograms.
+type metrics struct {
+	registry         *prometheus.Registry
+	requestDuration  *prometheus.HistogramVec
+	providerDuration *prometheus.HistogramVec
+	cacheRequests    *prometheus.CounterVec
+	failovers        *prometheus.CounterVec
+}
+
+func newMetrics() *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Duration of HTTP requests by route, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "code"}),
+		providerDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "provider_request_duration_seconds",
+			Help:      "Duration of upstream provider calls by provider, operation and outcome.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"provider", "operation", "outcome"}),
+		cacheRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "cache_requests_total",
+			Help:      "Cache lookups by cache and result (hit, miss or stale).",
+		}, []string{"cache", "result"}),
+		failovers: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "failovers_total",
+			Help:      "Requests answered by a provider after an earlier provider in the chain failed.",
+		}, []string{"operation"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requestDuration,
+		m.providerDuration,
+		m.cacheRequests,
+		m.failovers,
+	)
+
+	return m
+}
+
+// MetricsHandler returns the handler that exposes the service metrics in the
+// Prometheus format.
+func (s *Service) MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(s.metrics.registry, promhttp.HandlerOpts{})
+}
+
+// MetricsMiddleware records the duration and status code of every request.
+// Errors are handled by the middleware so that their status codes are known,
+// and are still returned for outer middleware such as request logging.
+func (s *Service) MetricsMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			start := time.Now()
+			err := next(ctx)
+			if err != nil {
+				ctx.Error(err)
+			}
+			s.metrics.requestDuration.
+				WithLabelValues(ctx.Path(), ctx.Request().Method, strconv.Itoa(ctx.Response().Status)).
+				Observe(time.Since(start).Seconds())
+			return err
+		}
+	}
+}
+
+// observeProvider records the duration and outcome of a provider call.
+func (m *metrics) observeProvider(p provider, desc string, err error, duration time.Duration) {
+	m.providerDuration.WithLabelValues(p.name(), operation(desc), providerOutcome(err)).Observe(duration.Seconds())
+}
+
+func providerOutcome(err error) string {
+	switch {
+	case errors.Is(err, weather.ErrImplausibleObservation):
+		return outcomeImplausible
+	case err != nil:
+		return outcomeError
+	default:
+		return outcomeSuccess
+	}
+}
+
+func (m *metrics) observeCache(desc string, result string) {
+	m.cacheRequests.WithLabelValues(operation(desc), result).Inc()
+}
+
+func (m *metrics) observeFailover(desc string) {
+	m.failovers.WithLabelValues(operation(desc)).Inc()
+}
+
+// registerCacheAge exposes the age of the value cache entry as a gauge, which
+// is NaN until a value is cached.
+func registerCacheAge[T any](m *metrics, desc string, cache *valueCache[result[T]]) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   metricsNamespace,
+		Name:        "cache_age_seconds",
+		Help:        "Time since the cached value was fetched from a provider.",
+		ConstLabels: prometheus.Labels{"cache": operation(desc)},
+	}, func() float64 {
+		res, ok := cache.get()
+		if !ok {
+			return math.NaN()
+		}
+		return res.age().Seconds()
+	}))
+}
+
+// operation converts a description used in logs to a label value.
+func operation(desc string) string {
+	return strings.ReplaceAll(desc, " ", "_")
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_metrics(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+
+	s := newTestService(&mockWeatherStackClient{wantErr: true}, &mockOpenWeatherClient{})
+	e.Use(s.MetricsMiddleware())
+	e.GET("/v1/weather", s.GetWeather)
+	e.GET("/metrics", echo.WrapHandler(s.MetricsHandler()))
+
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	// Miss with fail over, then a hit
+	require.Equal(t, http.StatusOK, get("/v1/weather?city="+wantCity).Code)
+	require.Equal(t, http.StatusOK, get("/v1/weather?city="+wantCity).Code)
+	require.Equal(t, http.StatusBadRequest, get("/v1/weather?city=melbourne").Code)
+
+	// Stale rather than a miss once every provider fails
+	time.Sleep(100*time.Millisecond + time.Millisecond)
+	failing := newTestService(&mockWeatherStackClient{wantErr: true}, &mockOpenWeatherClient{wantErr: true})
+	s.providers, s.outliers = failing.providers, failing.outliers
+	require.Equal(t, http.StatusOK, get("/v1/weather?city="+wantCity).Code)
+
+	m := s.metrics
+	require.Equal(t, 1.0, testutil.ToFloat64(m.cacheRequests.WithLabelValues("weather", cacheHit)))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.cacheRequests.WithLabelValues("weather", cacheMiss)))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.cacheRequests.WithLabelValues("weather", cacheStale)))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.failovers.WithLabelValues("weather")))
+
+	body := scrape(t, s)
+	for _, want := range []string{
+		`sydneyweather_http_request_duration_seconds_count{code="200",method="GET",route="/v1/weather"} 3`,
+		`sydneyweather_http_request_duration_seconds_count{code="400",method="GET",route="/v1/weather"} 1`,
+		`sydneyweather_provider_request_duration_seconds_count{operation="weather",outcome="error",provider="weatherstack"} 2`,
+		`sydneyweather_provider_request_duration_seconds_count{operation="weather",outcome="success",provider="openweather"} 1`,
+		`sydneyweather_provider_request_duration_seconds_count{operation="weather",outcome="error",provider="openweather"} 1`,
+		`sydneyweather_cache_age_seconds{cache="forecast"} NaN`,
+	} {
+		require.Contains(t, body, want)
+	}
+
+	match := regexp.MustCompile(`sydneyweather_cache_age_seconds{cache="weather"} (\S+)`).FindStringSubmatch(body)
+	require.Len(t, match, 2)
+	age, err := strconv.ParseFloat(match[1], 64)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, age, 0.1)
+}
+
+func TestService_metrics_implausible(t *testing.T) {
+	s := newTestService(&implausibleWeatherStackClient{}, &mockOpenWeatherClient{})
+	_, err := s.currentObservation(context.Background())
+	require.NoError(t, err)
+
+	require.Contains(t, scrape(t, s), `sydneyweather_provider_request_duration_seconds_count{operation="weather",outcome="implausible",provider="weatherstack"} 1`)
+}
+
+func scrape(t *testing.T, s *Service) string {
+	rec := httptest.NewRecorder()
+	s.MetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	return rec.Body.String()
+}
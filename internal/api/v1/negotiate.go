@@ -0,0 +1,220 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// format is a representation a response can be rendered in.
+type format string
+
+const (
+	formatJSON format = "json"
+	formatXML  format = "xml"
+	formatCSV  format = "csv"
+	formatText format = "text"
+)
+
+const mimeTextCSVCharsetUTF8 = "text/csv; charset=UTF-8" // Not defined by echo
+
+// offers are the media types that can be negotiated, in order of preference
+// when a wildcard or several types are accepted with the same quality.
+var offers = []struct {
+	mediaType string
+	format    format
+}{
+	{echo.MIMEApplicationJSON, formatJSON},
+	{echo.MIMETextPlain, formatText},
+	{echo.MIMEApplicationXML, formatXML},
+	{echo.MIMETextXML, formatXML},
+	{"text/csv", formatCSV},
+}
+
+// negotiable is a response that can be rendered in every format. JSON and XML
+// are rendered from struct tags.
+type negotiable interface {
+	// csvRecords returns the header and rows of the response.
+	csvRecords() [][]string
+	// summary returns the response as a human-readable single line.
+	summary() string
+}
+
+// negotiate returns the format to render a response in. The 'format' query
+// param takes precedence over the Accept header. JSON is used when neither are
+// set and a 406 error is returned when no supported format is acceptable.
+// Browsers, which accept HTML, get JSON whenever they accept it rather than the
+// XML they rank above the '*/*' wildcard. The Vary header is set up front since
+// every response, including 304 Not Modified, depends on the Accept header.
+func negotiate(ctx echo.Context) (format, error) {
+	ctx.Response().Header().Add(echo.HeaderVary, echo.HeaderAccept)
+
+	if param := ctx.QueryParam("format"); param != "" {
+		switch f := format(strings.ToLower(param)); f {
+		case formatJSON, formatXML, formatCSV, formatText:
+			return f, nil
+		}
+		return "", errNotAcceptable()
+	}
+
+	accept := ctx.Request().Header.Get(echo.HeaderAccept)
+	if accept == "" {
+		return formatJSON, nil
+	}
+
+	groups := parseAccept(accept)
+	if acceptsHTML(groups) {
+		for _, ranges := range groups {
+			if matchesAny(ranges, echo.MIMEApplicationJSON) {
+				return formatJSON, nil
+			}
+		}
+	}
+
+	for _, ranges := range groups {
+		for _, offer := range offers {
+			if matchesAny(ranges, offer.mediaType) {
+				return offer.format, nil
+			}
+		}
+	}
+
+	return "", errNotAcceptable()
+}
+
+// render writes the response in the format, which must have been negotiated.
+func render(ctx echo.Context, f format, code int, v negotiable) error {
+	switch f {
+	case formatXML:
+		return ctx.XML(code, v)
+	case formatCSV:
+		var buf bytes.Buffer
+		if err := csv.NewWriter(&buf).WriteAll(v.csvRecords()); err != nil {
+			return err
+		}
+		return ctx.Blob(code, mimeTextCSVCharsetUTF8, buf.Bytes())
+	case formatText:
+		return ctx.String(code, v.summary()+"\n")
+	default:
+		return ctx.JSON(code, v)
+	}
+}
+
+func errNotAcceptable() error {
+	return echo.NewHTTPError(http.StatusNotAcceptable, "supported formats are json, xml, csv and text")
+}
+
+// parseAccept returns the media ranges of an Accept header grouped by quality,
+// highest first. Ranges with zero quality are not acceptable and are excluded.
+func parseAccept(accept string) [][]string {
+	type mediaRange struct {
+		value   string
+		quality float64
+	}
+
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		r := mediaRange{value: strings.ToLower(strings.TrimSpace(params[0])), quality: 1}
+		for _, param := range params[1:] {
+			k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || strings.ToLower(k) != "q" {
+				continue
+			}
+			if q, err := strconv.ParseFloat(v, 64); err == nil {
+				r.quality = q
+			}
+		}
+		if r.value != "" && r.quality > 0 {
+			ranges = append(ranges, r)
+		}
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].quality > ranges[j].quality
+	})
+
+	var groups [][]string
+	for i, r := range ranges {
+		if i == 0 || r.quality != ranges[i-1].quality {
+			groups = append(groups, nil)
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], r.value)
+	}
+	return groups
+}
+
+// acceptsHTML returns true if the media ranges explicitly include HTML, which
+// only browsers ask for.
+func acceptsHTML(groups [][]string) bool {
+	for _, ranges := range groups {
+		for _, r := range ranges {
+			if r == echo.MIMETextHTML {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchesAny returns true if the media type is within any of the media ranges.
+func matchesAny(ranges []string, mediaType string) bool {
+	for _, r := range ranges {
+		if mediaTypeMatches(r, mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// mediaTypeMatches returns true if the media type is within the media range,
+// which may be a wildcard such as '*/*' or 'text/*'.
+func mediaTypeMatches(mediaRange string, mediaType string) bool {
+	if mediaRange == "*/*" || mediaRange == mediaType {
+		return true
+	}
+	rangeType, rangeSubtype, _ := strings.Cut(mediaRange, "/")
+	typ, _, _ := strings.Cut(mediaType, "/")
+	return rangeSubtype == "*" && rangeType == typ
+}
+
+// Helpers for formatting optional values as CSV fields, which are empty when
+// the value is nil.
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func formatOptFloat(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return formatFloat(*v)
+}
+
+func formatOptInt(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
+
+func formatOptString(v *string) string {
+	if v == nil {
+		return ""
+	}
+	return *v
+}
+
+func formatOptTime(v *time.Time) string {
+	if v == nil {
+		return ""
+	}
+	return v.Format(time.RFC3339)
+}
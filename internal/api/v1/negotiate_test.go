@@ -0,0 +1,259 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name    string
+		param   string
+		accept  string
+		want    format
+		wantErr bool
+	}{
+		{name: "default", want: formatJSON},
+		{name: "json", accept: "application/json", want: formatJSON},
+		{name: "xml", accept: "application/xml", want: formatXML},
+		{name: "text xml", accept: "text/xml", want: formatXML},
+		{name: "csv", accept: "text/csv", want: formatCSV},
+		{name: "text", accept: "text/plain", want: formatText},
+		{name: "any", accept: "*/*", want: formatJSON},
+		{name: "any text", accept: "text/*", want: formatText},
+		{name: "parameters", accept: "text/csv; charset=utf-8", want: formatCSV},
+		{name: "quality", accept: "application/json;q=0.5, text/csv", want: formatCSV},
+		{name: "tied", accept: "text/csv, application/xml, application/json", want: formatJSON},
+		{name: "tied wildcard", accept: "application/xml, */*", want: formatJSON},
+		{name: "browser", accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", want: formatJSON},
+		{name: "browser without wildcard", accept: "text/html,application/xml;q=0.9", want: formatXML},
+		{name: "unsupported skipped", accept: "image/png, text/plain", want: formatText},
+		{name: "not acceptable", accept: "image/png", wantErr: true},
+		{name: "zero quality", accept: "application/json;q=0", wantErr: true},
+		{name: "param", param: "CSV", accept: "application/json", want: formatCSV},
+		{name: "unsupported param", param: "yaml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/weather?format="+tt.param, nil)
+			if tt.accept != "" {
+				req.Header.Set(echo.HeaderAccept, tt.accept)
+			}
+			got, err := negotiate(echo.New().NewContext(req, httptest.NewRecorder()))
+			if tt.wantErr {
+				require.Equal(t, errNotAcceptable(), err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestService_GetWeather_formats(t *testing.T) {
+	tests := []struct {
+		name            string
+		accept          string
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "json",
+			accept:          echo.MIMEApplicationJSON,
+			wantContentType: echo.MIMEApplicationJSONCharsetUTF8,
+			wantBody:        `{"wind_speed":20,"temperature_degrees":10}` + "\n",
+		},
+		{
+			name:            "xml",
+			accept:          echo.MIMEApplicationXML,
+			wantContentType: echo.MIMEApplicationXMLCharsetUTF8,
+			wantBody:        xml.Header + "<weather><wind_speed>20</wind_speed><temperature_degrees>10</temperature_degrees></weather>",
+		},
+		{
+			name:            "csv",
+			accept:          "text/csv",
+			wantContentType: mimeTextCSVCharsetUTF8,
+			wantBody:        "wind_speed,temperature_degrees\n20,10\n",
+		},
+		{
+			name:            "text",
+			accept:          echo.MIMETextPlain,
+			wantContentType: echo.MIMETextPlainCharsetUTF8,
+			wantBody:        "10°C, wind speed 20\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/v1/weather?city="+wantCity, nil)
+			req.Header.Set(echo.HeaderAccept, tt.accept)
+			rec := httptest.NewRecorder()
+
+			s := newTestService(&mockWeatherStackClient{}, &mockOpenWeatherClient{})
+			require.NoError(t, s.GetWeather(e.NewContext(req, rec)))
+			require.Equal(t, http.StatusOK, rec.Code)
+			require.Equal(t, tt.wantContentType, rec.Header().Get(echo.HeaderContentType))
+			require.Equal(t, echo.HeaderAccept, rec.Header().Get(echo.HeaderVary))
+			require.Equal(t, tt.wantBody, rec.Body.String())
+			require.True(t, strings.HasSuffix(rec.Header().Get(headerETag), "-"+tt.name+`"`))
+		})
+	}
+}
+
+func TestService_GetWeather_notAcceptable(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/v1/weather?city="+wantCity, nil)
+	req.Header.Set(echo.HeaderAccept, "image/png")
+
+	s := newTestService(&mockWeatherStackClient{}, &mockOpenWeatherClient{})
+	err := s.GetWeather(e.NewContext(req, httptest.NewRecorder()))
+	require.EqualError(t, err, "code=406, message=supported formats are json, xml, csv and text")
+}
+
+func TestService_GetExtendedWeather_formats(t *testing.T) {
+	e := echo.New()
+	s := newTestService(&mockWeatherStackClient{}, &mockOpenWeatherClient{})
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v2/weather?city="+wantCity+"&format=csv", nil)
+	require.NoError(t, s.GetExtendedWeather(e.NewContext(req, rec)))
+
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	require.Len(t, records[1], len(records[0]))
+	require.Equal(t, []string{"source", "observed_at"}, records[0][:2])
+	require.Equal(t, "weatherstack", records[1][0])
+	require.Equal(t, "10", records[1][5])
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/v2/weather?city="+wantCity+"&format=xml", nil)
+	require.NoError(t, s.GetExtendedWeather(e.NewContext(req, rec)))
+
+	var resp ExtendedWeatherResponse
+	require.NoError(t, xml.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, float64(wantTemp), resp.TempDegrees)
+	require.Equal(t, "weatherstack", resp.Provenance.Source)
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/v2/weather?city="+wantCity+"&format=text", nil)
+	require.NoError(t, s.GetExtendedWeather(e.NewContext(req, rec)))
+	require.Equal(t, "10.0°C, wind 20 km/h (weatherstack)\n", rec.Body.String())
+}
+
+func TestService_GetHistory_formats(t *testing.T) {
+	e := echo.New()
+	s := newTestService(&mockWeatherStackClient{}, &mockOpenWeatherClient{})
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v2/weather/history?city="+wantCity+"&date=2022-05-01", nil)
+	req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationXML)
+	require.NoError(t, s.GetHistory(e.NewContext(req, rec)))
+	require.True(t, strings.HasPrefix(rec.Body.String(), xml.Header+"<history>"))
+
+	var resp HistoryResponse
+	require.NoError(t, xml.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, "2022-05-01", resp.Date)
+	require.Equal(t, float64(wantTemp), resp.TempDegrees)
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/v2/weather/history?city="+wantCity+"&date=2022-05-01&format=csv", nil)
+	require.NoError(t, s.GetHistory(e.NewContext(req, rec)))
+
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	require.NoError(t, err)
+	require.Equal(t, []string{"date", "time", "source"}, records[0][:3])
+	require.Equal(t, "2022-05-01", records[1][0])
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/v2/weather/history?city="+wantCity+"&date=2022-05-01&format=text", nil)
+	require.NoError(t, s.GetHistory(e.NewContext(req, rec)))
+	require.Equal(t, "2022-05-01: 10.0°C, wind 20 km/h (weatherstack)\n", rec.Body.String())
+}
+
+func TestService_v2Formats(t *testing.T) {
+	s := newTestService(&mockWeatherStackClient{}, &mockOpenWeatherClient{})
+
+	tests := []struct {
+		name        string
+		handler     echo.HandlerFunc
+		path        string
+		wantXMLRoot string
+		wantCSVHead string
+		wantText    string
+	}{
+		{
+			name:        "forecast",
+			handler:     s.GetForecast,
+			path:        "/v2/weather/forecast?city=" + wantCity,
+			wantXMLRoot: "<forecast>",
+			wantCSVHead: "time",
+			wantText:    "°C",
+		},
+		{
+			name:        "alerts",
+			handler:     s.GetAlerts,
+			path:        "/v2/weather/alerts?city=" + wantCity,
+			wantXMLRoot: "<alerts>",
+			wantCSVHead: "source",
+			wantText:    "active alert",
+		},
+		{
+			name:        "air quality",
+			handler:     s.GetAirQuality,
+			path:        "/v2/air-quality?city=" + wantCity,
+			wantXMLRoot: "<air_quality>",
+			wantCSVHead: "source",
+			wantText:    "AQI 102",
+		},
+		{
+			name:        "astronomy",
+			handler:     s.GetAstronomy,
+			path:        "/v2/astronomy?city=" + wantCity + "&date=2022-06-21",
+			wantXMLRoot: "<astronomy>",
+			wantCSVHead: "date",
+			wantText:    "2022-06-21: sunrise 06:59, sunset 16:53",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			serve := func(accept string) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+				req.Header.Set(echo.HeaderAccept, accept)
+				rec := httptest.NewRecorder()
+				require.NoError(t, tt.handler(e.NewContext(req, rec)))
+				require.Equal(t, echo.HeaderAccept, rec.Header().Get(echo.HeaderVary))
+				return rec
+			}
+
+			rec := serve(echo.MIMEApplicationXML)
+			require.True(t, strings.HasPrefix(rec.Body.String(), xml.Header+tt.wantXMLRoot), rec.Body.String())
+
+			rec = serve("text/csv")
+			r := csv.NewReader(rec.Body)
+			r.FieldsPerRecord = -1
+			records, err := r.ReadAll()
+			require.NoError(t, err)
+			require.Equal(t, tt.wantCSVHead, records[0][0])
+
+			rec = serve(echo.MIMETextPlain)
+			require.Contains(t, rec.Body.String(), tt.wantText)
+			require.Equal(t, 1, strings.Count(rec.Body.String(), "\n"))
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set(echo.HeaderAccept, "image/png")
+			require.Equal(t, errNotAcceptable(), tt.handler(e.NewContext(req, httptest.NewRecorder())))
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/joshjon/sydneyweather/internal/logging"
+)
+
+const mimeApplicationProblemJSON = "application/problem+json"
+
+// Problem types. Statuses without a specific type use 'about:blank' as per
+// RFC 7807, in which case the title is the status text.
+const (
+	problemTypeBlank                = "about:blank"
+	problemTypeInvalidRequest       = "urn:sydneyweather:problem:invalid-request"
+	problemTypeUnauthorized         = "urn:sydneyweather:problem:unauthorized"
+	problemTypeForbidden            = "urn:sydneyweather:problem:forbidden"
+	problemTypeNotAcceptable        = "urn:sydneyweather:problem:not-acceptable"
+	problemTypeRateLimited          = "urn:sydneyweather:problem:rate-limited"
+	problemTypeProvidersUnavailable = "urn:sydneyweather:problem:providers-unavailable"
+)
+
+// problemTypes are the specific problem types and their titles by status.
+var problemTypes = map[int]struct{ uri, title string }{
+	http.StatusBadRequest:         {problemTypeInvalidRequest, "Invalid request"},
+	http.StatusUnauthorized:       {problemTypeUnauthorized, "API key required"},
+	http.StatusForbidden:          {problemTypeForbidden, "Not permitted"},
+	http.StatusNotAcceptable:      {problemTypeNotAcceptable, "Format not acceptable"},
+	http.StatusTooManyRequests:    {problemTypeRateLimited, "Too many requests"},
+	http.StatusServiceUnavailable: {problemTypeProvidersUnavailable, "Weather providers unavailable"},
+}
+
+// Problem is an RFC 7807 problem details response. Providers lists why each
+// provider in the chain failed when none could answer.
+type Problem struct {
+	Type      string            `json:"type"`
+	Title     string            `json:"title"`
+	Status    int               `json:"status"`
+	Detail    string            `json:"detail,omitempty"`
+	Instance  string            `json:"instance,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+	Providers []ProviderFailure `json:"providers,omitempty"`
+}
+
+type ProviderFailure struct {
+	Provider string `json:"provider"`
+	Error    string `json:"error"`
+}
+
+// chainError is the internal error of the HTTP error returned when every
+// provider in the chain fails.
+type chainError struct {
+	desc     string
+	failures []ProviderFailure
+}
+
+func (e *chainError) Error() string {
+	return fmt.Sprintf("no provider could return %s", e.desc)
+}
+
+// HTTPErrorHandler is an echo error handler that responds with RFC 7807 problem
+// details. Errors that are not HTTP errors are reported as internal server
+// errors without detail, since their messages are not meant for clients, and
+// are logged instead.
+func HTTPErrorHandler(err error, ctx echo.Context) {
+	if ctx.Response().Committed {
+		return
+	}
+
+	logger := logging.FromContext(ctx.Request().Context())
+	var httpErr *echo.HTTPError
+	if !errors.As(err, &httpErr) {
+		logger.Error("internal error", logging.KeyError, err)
+	}
+
+	problem := newProblem(err)
+	problem.Instance = ctx.Request().URL.Path
+	problem.RequestID = ctx.Response().Header().Get(echo.HeaderXRequestID)
+	if problem.RequestID == "" {
+		problem.RequestID = ctx.Request().Header.Get(echo.HeaderXRequestID)
+	}
+
+	ctx.Response().Header().Set(echo.HeaderContentType, mimeApplicationProblemJSON)
+
+	if ctx.Request().Method == http.MethodHead {
+		err = ctx.NoContent(problem.Status)
+	} else {
+		err = ctx.JSON(problem.Status, problem)
+	}
+	if err != nil {
+		logger.Error("error writing problem response", logging.KeyError, err)
+	}
+}
+
+func newProblem(err error) *Problem {
+	var httpErr *echo.HTTPError
+	if !errors.As(err, &httpErr) {
+		httpErr = echo.NewHTTPError(http.StatusInternalServerError)
+	}
+
+	problem := &Problem{
+		Type:   problemTypeBlank,
+		Title:  http.StatusText(httpErr.Code),
+		Status: httpErr.Code,
+	}
+
+	if t, ok := problemTypes[httpErr.Code]; ok {
+		problem.Type, problem.Title = t.uri, t.title
+	}
+
+	if msg, ok := httpErr.Message.(string); ok && msg != http.StatusText(httpErr.Code) {
+		problem.Detail = msg
+	}
+
+	var chainErr *chainError
+	if errors.As(err, &chainErr) {
+		problem.Detail = chainErr.Error()
+		problem.Providers = chainErr.failures
+	}
+
+	return problem
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPErrorHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want Problem
+	}{
+		{
+			name: "invalid request",
+			err:  echo.NewHTTPError(http.StatusBadRequest, "query param 'city' must have value 'sydney'"),
+			want: Problem{
+				Type:   problemTypeInvalidRequest,
+				Title:  "Invalid request",
+				Status: http.StatusBadRequest,
+				Detail: "query param 'city' must have value 'sydney'",
+			},
+		},
+		{
+			name: "not acceptable",
+			err:  errNotAcceptable(),
+			want: Problem{
+				Type:   problemTypeNotAcceptable,
+				Title:  "Format not acceptable",
+				Status: http.StatusNotAcceptable,
+				Detail: "supported formats are json, xml, csv and text",
+			},
+		},
+		{
+			name: "rate limited",
+			err:  echo.NewHTTPError(http.StatusTooManyRequests, "daily quota exceeded"),
+			want: Problem{
+				Type:   problemTypeRateLimited,
+				Title:  "Too many requests",
+				Status: http.StatusTooManyRequests,
+				Detail: "daily quota exceeded",
+			},
+		},
+		{
+			name: "providers unavailable",
+			err: echo.NewHTTPError(http.StatusServiceUnavailable).SetInternal(&chainError{
+				desc:     "weather",
+				failures: []ProviderFailure{{Provider: "weatherstack", Error: "some-error"}},
+			}),
+			want: Problem{
+				Type:      problemTypeProvidersUnavailable,
+				Title:     "Weather providers unavailable",
+				Status:    http.StatusServiceUnavailable,
+				Detail:    "no provider could return weather",
+				Providers: []ProviderFailure{{Provider: "weatherstack", Error: "some-error"}},
+			},
+		},
+		{
+			name: "status without type",
+			err:  echo.ErrNotFound,
+			want: Problem{
+				Type:   problemTypeBlank,
+				Title:  "Not Found",
+				Status: http.StatusNotFound,
+			},
+		},
+		{
+			name: "internal error",
+			err:  errors.New("some-error"),
+			want: Problem{
+				Type:   problemTypeBlank,
+				Title:  "Internal Server Error",
+				Status: http.StatusInternalServerError,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/v1/weather", nil)
+			rec := httptest.NewRecorder()
+			ctx := e.NewContext(req, rec)
+			ctx.Response().Header().Set(echo.HeaderXRequestID, "some-request-id")
+
+			HTTPErrorHandler(tt.err, ctx)
+			require.Equal(t, tt.want.Status, rec.Code)
+			require.Equal(t, mimeApplicationProblemJSON, rec.Header().Get(echo.HeaderContentType))
+
+			var got Problem
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+			tt.want.Instance, tt.want.RequestID = "/v1/weather", "some-request-id"
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestHTTPErrorHandler_providerFailures(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	s := newTestService(&mockWeatherStackClient{wantErr: true}, &mockOpenWeatherClient{wantErr: true})
+	e.GET("/v1/weather", s.GetWeather)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/weather?city="+wantCity, nil)
+	req.Header.Set(echo.HeaderXRequestID, "some-request-id")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	var got Problem
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Equal(t, "some-request-id", got.RequestID)
+	require.Equal(t, []ProviderFailure{
+		{Provider: "weatherstack", Error: "some-error"},
+		{Provider: "openweather", Error: "some-error"},
+	}, got.Providers)
+}
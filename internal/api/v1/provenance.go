@@ -0,0 +1,80 @@
+package api
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Provenance headers set on v1 responses, which have no room in their body.
+const (
+	headerSource     = "X-Weather-Source"
+	headerObservedAt = "X-Weather-Observed-At"
+	headerFetchedAt  = "X-Weather-Fetched-At"
+	headerStale      = "X-Weather-Stale"
+	headerCacheAge   = "X-Weather-Cache-Age"
+)
+
+// result is a value retrieved from a provider along with its provenance.
+type result[T any] struct {
+	value     *T
+	source    string
+	fetchedAt time.Time
+	// stale indicates the value was served from an expired cache entry because
+	// every provider failed.
+	stale bool
+}
+
+// age returns how long ago the value was fetched from the provider.
+func (r *result[T]) age() time.Duration {
+	return time.Since(r.fetchedAt)
+}
+
+// ProvenanceResponse describes where the data in a response came from and how
+// old it is.
+type ProvenanceResponse struct {
+	Source          string     `json:"source" xml:"source"`
+	ObservedAt      *time.Time `json:"observed_at" xml:"observed_at"`
+	FetchedAt       time.Time  `json:"fetched_at" xml:"fetched_at"`
+	Stale           bool       `json:"stale" xml:"stale"`
+	CacheAgeSeconds int        `json:"cache_age_seconds" xml:"cache_age_seconds"`
+}
+
+// newProvenanceResponse creates a provenance response for the result. The
+// upstream observation time is only known for observations.
+func newProvenanceResponse[T any](r *result[T], observedAt *time.Time) *ProvenanceResponse {
+	return &ProvenanceResponse{
+		Source:          r.source,
+		ObservedAt:      observedAt,
+		FetchedAt:       r.fetchedAt.UTC(),
+		Stale:           r.stale,
+		CacheAgeSeconds: int(r.age().Seconds()),
+	}
+}
+
+// csvFields returns the CSV header and row of the provenance, which are the
+// leading columns of responses that have one. The row is empty when there is no
+// provenance.
+func (p *ProvenanceResponse) csvFields() ([]string, []string) {
+	header := []string{"source", "observed_at", "fetched_at", "stale", "cache_age_seconds"}
+	if p == nil {
+		return header, make([]string, len(header))
+	}
+	return header, []string{
+		p.Source, formatOptTime(p.ObservedAt), p.FetchedAt.Format(time.RFC3339),
+		strconv.FormatBool(p.Stale), strconv.Itoa(p.CacheAgeSeconds),
+	}
+}
+
+// setProvenanceHeaders sets the provenance of the response as headers.
+func setProvenanceHeaders(ctx echo.Context, p *ProvenanceResponse) {
+	h := ctx.Response().Header()
+	h.Set(headerSource, p.Source)
+	if p.ObservedAt != nil {
+		h.Set(headerObservedAt, p.ObservedAt.UTC().Format(time.RFC3339))
+	}
+	h.Set(headerFetchedAt, p.FetchedAt.Format(time.RFC3339))
+	h.Set(headerStale, strconv.FormatBool(p.Stale))
+	h.Set(headerCacheAge, strconv.Itoa(p.CacheAgeSeconds))
+}
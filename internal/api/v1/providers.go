@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/joshjon/sydneyweather/internal/weather"
+)
+
+type WeatherStackClient interface {
+	GetWeather(ctx context.Context, city string) (*weather.WeatherStackResponse, error)
+	GetForecast(ctx context.Context, city string) (*weather.WeatherStackForecastResponse, error)
+	GetHistory(ctx context.Context, city string, t time.Time) (*weather.WeatherStackHistoryResponse, error)
+}
+
+type OpenWeatherClient interface {
+	GetWeather(ctx context.Context, city string) (*weather.OpenWeatherResponse, error)
+	GetForecast(ctx context.Context, lat float64, lon float64) (*weather.OpenWeatherOneCallResponse, error)
+	GetHistory(ctx context.Context, lat float64, lon float64, t time.Time) (*weather.OpenWeatherTimeMachineResponse, error)
+	GetAlerts(ctx context.Context, lat float64, lon float64) (*weather.OpenWeatherOneCallResponse, error)
+	GetAirPollution(ctx context.Context, lat float64, lon float64) (*weather.OpenWeatherAirPollutionResponse, error)
+}
+
+// errNotSupported is returned by a provider that does not support the requested
+// data, in which case the next provider in the chain is queried.
+var errNotSupported = errors.New("not supported by provider")
+
+// errPlanNotSupported is returned when a provider rejects a request for data
+// that its API plan does not include. The provider is still healthy, so the
+// rejection does not count towards its circuit.
+var errPlanNotSupported = errors.New("not included in provider plan")
+
+// provider is a weather source in the service's fail-over chain. Each provider
+// adapts a client to return data normalised by the weather package.
+type provider interface {
+	name() string
+	current(ctx context.Context, loc weather.Location) (*weather.Observation, error)
+	forecast(ctx context.Context, loc weather.Location) (*weather.Forecast, error)
+	history(ctx context.Context, loc weather.Location, t time.Time) (*weather.Observation, error)
+	alerts(ctx context.Context, loc weather.Location) ([]weather.Alert, error)
+	airQuality(ctx context.Context, loc weather.Location) (*weather.AirQuality, error)
+}
+
+type weatherStackProvider struct {
+	client WeatherStackClient
+}
+
+func (p *weatherStackProvider) name() string {
+	return "weatherstack"
+}
+
+func (p *weatherStackProvider) current(ctx context.Context, loc weather.Location) (*weather.Observation, error) {
+	resp, err := p.client.GetWeather(ctx, loc.Name)
+	if err != nil {
+		return nil, err
+	}
+	obs := resp.Observation()
+	return &obs, nil
+}
+
+func (p *weatherStackProvider) forecast(ctx context.Context, loc weather.Location) (*weather.Forecast, error) {
+	resp, err := p.client.GetForecast(ctx, loc.Name)
+	if err != nil {
+		return nil, err
+	}
+	forecast, err := resp.Forecast(loc.Zone, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &forecast, nil
+}
+
+func (p *weatherStackProvider) history(ctx context.Context, loc weather.Location, t time.Time) (*weather.Observation, error) {
+	resp, err := p.client.GetHistory(ctx, loc.Name, t)
+	if err != nil {
+		return nil, err
+	}
+	obs, err := resp.Observation(t.In(loc.Zone))
+	if err != nil {
+		return nil, err
+	}
+	return &obs, nil
+}
+
+func (p *weatherStackProvider) alerts(_ context.Context, _ weather.Location) ([]weather.Alert, error) {
+	return nil, errNotSupported
+}
+
+func (p *weatherStackProvider) airQuality(_ context.Context, _ weather.Location) (*weather.AirQuality, error) {
+	return nil, errNotSupported
+}
+
+type openWeatherProvider struct {
+	client OpenWeatherClient
+}
+
+func (p *openWeatherProvider) name() string {
+	return "openweather"
+}
+
+func (p *openWeatherProvider) current(ctx context.Context, loc weather.Location) (*weather.Observation, error) {
+	resp, err := p.client.GetWeather(ctx, loc.Name)
+	if err != nil {
+		return nil, err
+	}
+	obs := resp.Observation()
+	return &obs, nil
+}
+
+func (p *openWeatherProvider) forecast(ctx context.Context, loc weather.Location) (*weather.Forecast, error) {
+	resp, err := p.client.GetForecast(ctx, loc.Lat, loc.Lon)
+	if err != nil {
+		return nil, planError(err)
+	}
+	forecast := resp.Forecast(loc.Zone, time.Now())
+	return &forecast, nil
+}
+
+func (p *openWeatherProvider) history(ctx context.Context, loc weather.Location, t time.Time) (*weather.Observation, error) {
+	resp, err := p.client.GetHistory(ctx, loc.Lat, loc.Lon, t)
+	if err != nil {
+		return nil, planError(err)
+	}
+	obs, err := resp.Observation()
+	if err != nil {
+		return nil, err
+	}
+	return &obs, nil
+}
+
+func (p *openWeatherProvider) alerts(ctx context.Context, loc weather.Location) ([]weather.Alert, error) {
+	resp, err := p.client.GetAlerts(ctx, loc.Lat, loc.Lon)
+	if err != nil {
+		return nil, planError(err)
+	}
+	return resp.Alerts(loc.Zone), nil
+}
+
+func (p *openWeatherProvider) airQuality(ctx context.Context, loc weather.Location) (*weather.AirQuality, error) {
+	resp, err := p.client.GetAirPollution(ctx, loc.Lat, loc.Lon)
+	if err != nil {
+		return nil, planError(err)
+	}
+	aq, err := resp.AirQuality(loc.Zone)
+	if err != nil {
+		return nil, err
+	}
+	return &aq, nil
+}
+
+// planError marks an authorization failure of a One Call or air pollution
+// endpoint as data the plan does not include, since the same key is accepted
+// for current conditions, which every plan includes.
+func planError(err error) error {
+	var httpErr *weather.HTTPError
+	if errors.As(err, &httpErr) && (httpErr.StatusCode == http.StatusUnauthorized || httpErr.StatusCode == http.StatusForbidden) {
+		return fmt.Errorf("%w: %w", errPlanNotSupported, err)
+	}
+	return err
+}
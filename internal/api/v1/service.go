@@ -1,99 +1,502 @@
 package api
 
 import (
-	"log"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/labstack/echo/v4"
 
+	"github.com/joshjon/sydneyweather/internal/comfort"
+	"github.com/joshjon/sydneyweather/internal/logging"
 	"github.com/joshjon/sydneyweather/internal/weather"
 )
 
 const city = "sydney"
 
-type WeatherStackClient interface {
-	GetWeather(city string) (*weather.WeatherStackResponse, error)
-}
-
-type OpenWeatherClient interface {
-	GetWeather(city string) (*weather.OpenWeatherResponse, error)
+// location is the city supported by the service.
+var location = weather.Location{
+	Name: city,
+	Lat:  -33.8688,
+	Lon:  151.2093,
+	Zone: mustLoadZone("Australia/Sydney"),
 }
 
 // Service is an HTTP api that provides basic weather data for a given city.
 // The service is configured to only accept 'sydney' as a city, although, it can
 // be easily tweaked to support any city if required.
 type Service struct {
-	primary   WeatherStackClient
-	failOver  OpenWeatherClient
-	respCache *valueCache[*GetWeatherResponse]
+	providers     []provider
+	obsCache      *valueCache[result[weather.Observation]]
+	forecastCache *valueCache[result[weather.Forecast]]
+	historyCache  *mapCache[string, result[weather.Observation]]
+	alertsCache   *valueCache[result[[]weather.Alert]]
+	alertLog      *alertLog
+	airCache      *valueCache[result[weather.AirQuality]]
+	validator     *weather.Validator
+	outliers      *outlierMonitor
+	metrics       *metrics
+	health        *healthTracker
+
+	providerTimeout atomic.Int64 // Nanoseconds, 0 is no timeout
 }
 
 type Config struct {
-	WeatherStackAPIKey string
-	OpenWeatherAPIKey  string
-	CacheExpiry        time.Duration
+	WeatherStackAPIKey  string
+	OpenWeatherAPIKey   string
+	CacheExpiry         time.Duration
+	ForecastCacheExpiry time.Duration
+	// ProviderOrder names the providers queried first, in order. Unlisted
+	// providers follow in their default order.
+	ProviderOrder []string
+	// ProviderTimeout limits each provider call. Zero is no limit.
+	ProviderTimeout time.Duration
+	// Limits are the plausible observation ranges, which LocationLimits can
+	// override per location name. Unset bounds fall back to weather.DefaultLimits.
+	Limits         weather.Limits
+	LocationLimits map[string]weather.Limits
+	Outlier        OutlierConfig
+	Health         HealthConfig
 }
 
+// NewService creates a new service. Providers are queried in order: weatherstack
+// is the primary source and OpenWeather the fail over source, unless the order
+// is configured or one is demoted for deviating from the other providers. Call
+// Monitor to start sampling providers for outlier detection.
 func NewService(cfg Config) *Service {
-	return &Service{
-		primary:   weather.NewWeatherStackClient(cfg.WeatherStackAPIKey),
-		failOver:  weather.NewOpenWeatherClient(cfg.OpenWeatherAPIKey),
-		respCache: newValueCache[*GetWeatherResponse](cfg.CacheExpiry),
+	providers := []provider{
+		&weatherStackProvider{client: weather.NewWeatherStackClient(cfg.WeatherStackAPIKey)},
+		&openWeatherProvider{client: weather.NewOpenWeatherClient(cfg.OpenWeatherAPIKey)},
+	}
+	validator := weather.NewValidator(cfg.Limits, cfg.LocationLimits)
+
+	s := &Service{
+		providers:     providers,
+		obsCache:      newValueCache[result[weather.Observation]](cfg.CacheExpiry),
+		forecastCache: newValueCache[result[weather.Forecast]](cfg.ForecastCacheExpiry),
+		historyCache:  newMapCache[string, result[weather.Observation]](historyCacheSize),
+		alertsCache:   newValueCache[result[[]weather.Alert]](cfg.CacheExpiry),
+		alertLog:      newAlertLog(),
+		airCache:      newValueCache[result[weather.AirQuality]](cfg.CacheExpiry),
+		validator:     validator,
+		outliers:      newOutlierMonitor(cfg.Outlier, orderProviders(providers, cfg.ProviderOrder), []weather.Location{location}, validator),
+		metrics:       newMetrics(),
+		health:        newHealthTracker(cfg.Health),
+	}
+	s.providerTimeout.Store(int64(cfg.ProviderTimeout))
+	s.registerCacheAges()
+
+	return s
+}
+
+// Reload applies the settings that can change while requests are served: cache
+// expiries, provider order and timeout, and API keys. Cached values expire
+// according to the new expiry. Other settings are ignored.
+func (s *Service) Reload(cfg Config) {
+	s.obsCache.setTTL(cfg.CacheExpiry)
+	s.forecastCache.setTTL(cfg.ForecastCacheExpiry)
+	s.alertsCache.setTTL(cfg.CacheExpiry)
+	s.airCache.setTTL(cfg.CacheExpiry)
+	s.providerTimeout.Store(int64(cfg.ProviderTimeout))
+	s.outliers.reorder(orderProviders(s.providers, cfg.ProviderOrder), slog.Default())
+
+	for _, p := range s.providers {
+		switch p := p.(type) {
+		case *weatherStackProvider:
+			setAPIKey(p.client, cfg.WeatherStackAPIKey)
+		case *openWeatherProvider:
+			setAPIKey(p.client, cfg.OpenWeatherAPIKey)
+		}
+	}
+}
+
+// setAPIKey replaces the API key of clients that support it.
+func setAPIKey(client any, apiKey string) {
+	if c, ok := client.(interface{ SetAPIKey(apiKey string) }); ok {
+		c.SetAPIKey(apiKey)
 	}
 }
 
+// orderProviders returns the named providers in order followed by the rest in
+// their existing order. Unknown names are ignored.
+func orderProviders(providers []provider, names []string) []provider {
+	ordered := make([]provider, 0, len(providers))
+	listed := make(map[string]bool)
+	for _, name := range names {
+		for _, p := range providers {
+			if p.name() == name && !listed[name] {
+				ordered = append(ordered, p)
+				listed[name] = true
+			}
+		}
+	}
+	for _, p := range providers {
+		if !listed[p.name()] {
+			ordered = append(ordered, p)
+		}
+	}
+	return ordered
+}
+
+// registerCacheAges exposes the age of each value cache as a metric.
+func (s *Service) registerCacheAges() {
+	registerCacheAge(s.metrics, "weather", s.obsCache)
+	registerCacheAge(s.metrics, "forecast", s.forecastCache)
+	registerCacheAge(s.metrics, "alerts", s.alertsCache)
+	registerCacheAge(s.metrics, "air quality", s.airCache)
+}
+
 type GetWeatherResponse struct {
-	WindSpeed   int `json:"wind_speed"`
-	TempDegrees int `json:"temperature_degrees"`
+	XMLName     xml.Name `json:"-" xml:"weather"`
+	WindSpeed   int      `json:"wind_speed" xml:"wind_speed"`
+	TempDegrees int      `json:"temperature_degrees" xml:"temperature_degrees"`
+}
+
+func (r *GetWeatherResponse) csvRecords() [][]string {
+	return [][]string{
+		{"wind_speed", "temperature_degrees"},
+		{strconv.Itoa(r.WindSpeed), strconv.Itoa(r.TempDegrees)},
+	}
+}
+
+// summary leaves out the wind speed unit, which is the answering provider's.
+func (r *GetWeatherResponse) summary() string {
+	return fmt.Sprintf("%d°C, wind speed %d", r.TempDegrees, r.WindSpeed)
+}
+
+// ExtendedWeatherResponse describes the current conditions in more detail than
+// GetWeatherResponse. Fields the answering provider does not report are null.
+// Comfort metrics are computed rather than taken from the provider and are null
+// when they do not apply or their inputs are not reported. Provenance is omitted
+// when the response is part of a larger response that has its own.
+type ExtendedWeatherResponse struct {
+	XMLName          xml.Name            `json:"-" xml:"weather"`
+	Provenance       *ProvenanceResponse `json:"provenance,omitempty" xml:"provenance,omitempty"`
+	TempDegrees      float64             `json:"temperature_degrees" xml:"temperature_degrees"`
+	FeelsLikeDegrees *float64            `json:"feels_like_degrees" xml:"feels_like_degrees"`
+	DewPointDegrees  *float64            `json:"dew_point_degrees" xml:"dew_point_degrees"`
+	HeatIndexDegrees *float64            `json:"heat_index_degrees" xml:"heat_index_degrees"`
+	WindChillDegrees *float64            `json:"wind_chill_degrees" xml:"wind_chill_degrees"`
+	Humidity         *int                `json:"humidity_percent" xml:"humidity_percent"`
+	Pressure         *float64            `json:"pressure_hpa" xml:"pressure_hpa"`
+	CloudCover       *int                `json:"cloud_cover_percent" xml:"cloud_cover_percent"`
+	Visibility       *float64            `json:"visibility_km" xml:"visibility_km"`
+	WindSpeed        float64             `json:"wind_speed_kmh" xml:"wind_speed_kmh"`
+	WindDegree       *int                `json:"wind_degree" xml:"wind_degree"`
+	WindDirection    *string             `json:"wind_direction" xml:"wind_direction"`
+	BeaufortForce    int                 `json:"beaufort_force" xml:"beaufort_force"`
+	BeaufortDesc     string              `json:"beaufort_description" xml:"beaufort_description"`
+	WindGust         *float64            `json:"wind_gust_kmh" xml:"wind_gust_kmh"`
+	GustBeaufort     *int                `json:"wind_gust_beaufort_force" xml:"wind_gust_beaufort_force"`
+	Condition        string              `json:"condition" xml:"condition"`
+	Description      string              `json:"description" xml:"description"`
+	Icon             string              `json:"icon" xml:"icon"`
+}
+
+func (r *ExtendedWeatherResponse) csvRecords() [][]string {
+	header, row := r.csvFields()
+	return [][]string{header, row}
+}
+
+// csvFields returns the CSV header and row with the provenance flattened into
+// the leading columns.
+func (r *ExtendedWeatherResponse) csvFields() ([]string, []string) {
+	header, row := r.Provenance.csvFields()
+	header = append(header,
+		"temperature_degrees", "feels_like_degrees", "dew_point_degrees", "heat_index_degrees", "wind_chill_degrees",
+		"humidity_percent", "pressure_hpa", "cloud_cover_percent", "visibility_km",
+		"wind_speed_kmh", "wind_degree", "wind_direction", "beaufort_force", "beaufort_description",
+		"wind_gust_kmh", "wind_gust_beaufort_force", "condition", "description", "icon",
+	)
+
+	row = append(row,
+		formatFloat(r.TempDegrees), formatOptFloat(r.FeelsLikeDegrees), formatOptFloat(r.DewPointDegrees),
+		formatOptFloat(r.HeatIndexDegrees), formatOptFloat(r.WindChillDegrees),
+		formatOptInt(r.Humidity), formatOptFloat(r.Pressure), formatOptInt(r.CloudCover), formatOptFloat(r.Visibility),
+		formatFloat(r.WindSpeed), formatOptInt(r.WindDegree), formatOptString(r.WindDirection),
+		strconv.Itoa(r.BeaufortForce), r.BeaufortDesc,
+		formatOptFloat(r.WindGust), formatOptInt(r.GustBeaufort), r.Condition, r.Description, r.Icon,
+	)
+
+	return header, row
+}
+
+func (r *ExtendedWeatherResponse) summary() string {
+	parts := []string{fmt.Sprintf("%.1f°C", r.TempDegrees)}
+	if r.FeelsLikeDegrees != nil {
+		parts = append(parts, fmt.Sprintf("feels like %.1f°C", *r.FeelsLikeDegrees))
+	}
+	if r.Description != "" {
+		parts = append(parts, r.Description)
+	}
+
+	wind := fmt.Sprintf("wind %.0f km/h", r.WindSpeed)
+	if r.WindDirection != nil {
+		wind += " " + *r.WindDirection
+	}
+	parts = append(parts, wind)
+
+	if r.Humidity != nil {
+		parts = append(parts, fmt.Sprintf("humidity %d%%", *r.Humidity))
+	}
+
+	s := strings.Join(parts, ", ")
+	if r.Provenance != nil {
+		s += " (" + r.Provenance.Source + ")"
+	}
+	return s
 }
 
 // GetWeather returns the temperature and wind speed for the specified city.
-// Data retrieval is prioritized in the following order: cache (non expired),
-// primary source, fail over source, cache (stale).
+// The response is rendered in the negotiated format. Responses can be cached by
+// clients until the cache entry expires and conditional requests are answered
+// with 304 Not Modified.
 func (s *Service) GetWeather(ctx echo.Context) error {
-	if strings.ToLower(ctx.QueryParam("city")) != city {
-		return echo.NewHTTPError(http.StatusBadRequest, "query param 'city' must have value 'sydney'")
+	if err := validateCity(ctx); err != nil {
+		return err
 	}
 
-	if !s.respCache.expired() {
-		if resp, ok := s.respCache.get(); ok {
-			return ctx.JSON(http.StatusOK, resp)
-		}
+	f, err := negotiate(ctx)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.currentObservation(ctx.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	setProvenanceHeaders(ctx, newProvenanceResponse(res, res.value.ObservedAt))
+	setCacheHeaders(ctx, res, s.obsCache.ttl(), f)
+
+	if notModified(ctx) {
+		return ctx.NoContent(http.StatusNotModified)
+	}
+
+	// v1 has always served the provider's wind speed unit and truncated values
+	return render(ctx, f, http.StatusOK, &GetWeatherResponse{
+		WindSpeed:   int(res.value.ReportedWindSpeed),
+		TempDegrees: int(res.value.Temperature),
+	})
+}
+
+// GetExtendedWeather returns the full set of current conditions for the
+// specified city in the negotiated format.
+func (s *Service) GetExtendedWeather(ctx echo.Context) error {
+	if err := validateCity(ctx); err != nil {
+		return err
+	}
+
+	f, err := negotiate(ctx)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.currentObservation(ctx.Request().Context())
+	if err != nil {
+		return err
 	}
 
-	var resp *GetWeatherResponse
-	defer func() {
-		if resp != nil {
-			s.respCache.put(&resp)
+	resp := newExtendedWeatherResponse(res.value)
+	resp.Provenance = newProvenanceResponse(res, res.value.ObservedAt)
+
+	return render(ctx, f, http.StatusOK, resp)
+}
+
+func newExtendedWeatherResponse(obs *weather.Observation) *ExtendedWeatherResponse {
+	resp := &ExtendedWeatherResponse{
+		TempDegrees: obs.Temperature,
+		Humidity:    obs.Humidity,
+		Pressure:    obs.Pressure,
+		CloudCover:  obs.CloudCover,
+		Visibility:  obs.Visibility,
+		WindSpeed:   obs.WindSpeed,
+		WindDegree:  obs.WindDegree,
+		WindGust:    obs.WindGust,
+		Condition:   string(obs.Condition),
+		Description: obs.Description,
+		Icon:        obs.Icon(),
+	}
+
+	beaufort := weather.BeaufortForce(obs.WindSpeed, weather.KilometresPerHour)
+	resp.BeaufortForce, resp.BeaufortDesc = beaufort.Force, beaufort.Description
+
+	if obs.WindDegree != nil {
+		direction := weather.CompassDirection(float64(*obs.WindDegree))
+		resp.WindDirection = &direction
+	}
+
+	if obs.WindGust != nil {
+		gust := weather.BeaufortForce(*obs.WindGust, weather.KilometresPerHour)
+		resp.GustBeaufort = &gust.Force
+	}
+
+	if windChill, ok := comfort.WindChill(obs.Temperature, obs.WindSpeed); ok {
+		resp.WindChillDegrees = &windChill
+	}
+
+	if obs.Humidity != nil {
+		humidity := float64(*obs.Humidity)
+		feelsLike := comfort.ApparentTemperature(obs.Temperature, humidity, obs.WindSpeed)
+		resp.FeelsLikeDegrees = &feelsLike
+		if dewPoint, ok := comfort.DewPoint(obs.Temperature, humidity); ok {
+			resp.DewPointDegrees = &dewPoint
+		}
+		if heatIndex, ok := comfort.HeatIndex(obs.Temperature, humidity); ok {
+			resp.HeatIndexDegrees = &heatIndex
 		}
-	}()
+	}
 
-	primaryResp, err := s.primary.GetWeather(city)
-	if err == nil {
-		resp = &GetWeatherResponse{
-			WindSpeed:   primaryResp.Current.WindSpeed,
-			TempDegrees: primaryResp.Current.Temperature,
+	return resp
+}
+
+// currentObservation returns the current conditions for the city. Data
+// retrieval is prioritized in the following order: cache (non expired),
+// providers in order, cache (stale).
+func (s *Service) currentObservation(ctx context.Context) (*result[weather.Observation], error) {
+	return fetch(ctx, s.chain(), s.obsCache, "weather", func(ctx context.Context, p provider) (*weather.Observation, error) {
+		return s.validate(p.current(ctx, location))
+	})
+}
+
+// validate rejects an implausible observation as a provider failure, so the
+// next provider in the chain is queried rather than the observation cached.
+func (s *Service) validate(obs *weather.Observation, err error) (*weather.Observation, error) {
+	if err != nil {
+		return nil, err
+	}
+	if err = s.validator.Validate(location.Name, obs); err != nil {
+		return nil, err
+	}
+	return obs, nil
+}
+
+// providerChain is the providers to query in order, along with the timeout of
+// each call and where calls are recorded.
+type providerChain struct {
+	providers []provider
+	timeout   time.Duration
+	metrics   *metrics
+	health    *healthTracker
+}
+
+// chain returns the providers in the order they should be queried, with any
+// demoted providers last.
+func (s *Service) chain() *providerChain {
+	return &providerChain{
+		providers: s.outliers.chain(),
+		timeout:   time.Duration(s.providerTimeout.Load()),
+		metrics:   s.metrics,
+		health:    s.health,
+	}
+}
+
+// fetch returns the cache value if it has not expired, otherwise the result of
+// the first provider in the chain to succeed, which is then cached. The stale
+// cache value is returned if every provider fails.
+func fetch[T any](ctx context.Context, c *providerChain, cache *valueCache[result[T]], desc string, get func(ctx context.Context, p provider) (*T, error)) (*result[T], error) {
+	m := c.metrics
+	ctx, span := startCacheSpan(ctx, desc)
+	defer span.End()
+
+	if !cache.expired() {
+		if res, ok := cache.get(); ok {
+			m.observeCache(desc, cacheHit)
+			setCacheResult(span, cacheHit)
+			return res, nil
 		}
-		return ctx.JSON(http.StatusOK, resp)
 	}
-	log.Printf("error getting weather from primary source: %v\n", err)
 
-	failOverResp, err := s.failOver.GetWeather(city)
+	// Each lookup is counted once, as a miss or as stale once every provider
+	// has failed
+	res, err := query(ctx, c, desc, get)
 	if err == nil {
-		resp = &GetWeatherResponse{
-			WindSpeed:   int(failOverResp.Wind.Speed),
-			TempDegrees: int(failOverResp.Main.Temp),
+		cache.put(res)
+		m.observeCache(desc, cacheMiss)
+		setCacheResult(span, cacheMiss)
+		return res, nil
+	}
+
+	// Serve stale data
+	if res, ok := cache.get(); ok {
+		m.observeCache(desc, cacheStale)
+		setCacheResult(span, cacheStale)
+		stale := *res
+		stale.stale = true
+		return &stale, nil
+	}
+
+	m.observeCache(desc, cacheMiss)
+	setCacheResult(span, cacheMiss)
+	return nil, err
+}
+
+// query returns the result of the first provider in the chain to succeed.
+// Providers with an open circuit are skipped. The returned error summarises why
+// each provider failed when none succeed.
+func query[T any](ctx context.Context, c *providerChain, desc string, get func(ctx context.Context, p provider) (*T, error)) (*result[T], error) {
+	m, h, op := c.metrics, c.health, operation(desc)
+	chainErr := &chainError{desc: desc}
+	for _, p := range c.providers {
+		if !h.allow(p, op) {
+			chainErr.failures = append(chainErr.failures, ProviderFailure{Provider: p.name(), Error: errCircuitOpen.Error()})
+			continue
+		}
+
+		pctx, span := startProviderSpan(ctx, p, desc, len(chainErr.failures))
+		start := time.Now()
+		v, err := call(pctx, c.timeout, p, get)
+		if errors.Is(err, errNotSupported) {
+			span.End()
+			continue
+		}
+		duration := time.Since(start)
+		m.observeProvider(p, desc, err, duration)
+		h.record(p, op, err, duration, time.Now())
+		endProviderSpan(span, err)
+		if err == nil {
+			if len(chainErr.failures) > 0 {
+				m.observeFailover(desc)
+			}
+			return &result[T]{value: v, source: p.name(), fetchedAt: time.Now()}, nil
 		}
-		return ctx.JSON(http.StatusOK, resp)
+		logging.FromContext(ctx).Warn("provider request failed",
+			"operation", op, "provider", p.name(), logging.KeyError, err)
+		chainErr.failures = append(chainErr.failures, ProviderFailure{Provider: p.name(), Error: logging.Redact(err.Error())})
 	}
-	log.Printf("error getting weather from fail over source: %v\n", err)
 
-	// Serve stale weather data
-	if resp, ok := s.respCache.get(); ok {
-		return ctx.JSON(http.StatusOK, resp)
+	return nil, echo.NewHTTPError(http.StatusServiceUnavailable).SetInternal(chainErr)
+}
+
+// call returns the result of get for the provider, which is cancelled after
+// the timeout if set.
+func call[T any](ctx context.Context, timeout time.Duration, p provider, get func(ctx context.Context, p provider) (*T, error)) (*T, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
 	}
+	return get(ctx, p)
+}
 
-	return echo.NewHTTPError(http.StatusServiceUnavailable)
+func validateCity(ctx echo.Context) error {
+	if strings.ToLower(ctx.QueryParam("city")) != city {
+		return echo.NewHTTPError(http.StatusBadRequest, "query param 'city' must have value 'sydney'")
+	}
+	return nil
+}
+
+func mustLoadZone(name string) *time.Location {
+	zone, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return zone
 }
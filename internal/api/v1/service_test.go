@@ -1,23 +1,34 @@
 package api
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
 	"github.com/stretchr/testify/require"
 
+	"github.com/joshjon/sydneyweather/internal/logging"
 	"github.com/joshjon/sydneyweather/internal/weather"
 )
 
 const (
-	wantTemp  = 10
-	wantSpeed = 20
-	wantCity  = "Sydney"
+	wantTemp    = 10
+	wantMinTemp = 5
+	wantSpeed   = 20
+	wantCity    = "Sydney"
+
+	wantPM25 = 35.9 // AQI 102
+
+	wantAlertSource   = "Australian Government Bureau of Meteorology"
+	wantAlertHeadline = "Severe Thunderstorm Warning"
 )
 
 func TestNewService(t *testing.T) {
@@ -26,10 +37,70 @@ func TestNewService(t *testing.T) {
 		OpenWeatherAPIKey:  "ow-key",
 	}
 	s := NewService(cfg)
-	require.Equal(t, wantCity, wantCity)
-	require.NotNil(t, s.primary)
-	require.NotNil(t, s.failOver)
-	require.NotNil(t, s.respCache)
+	require.Len(t, s.providers, 2)
+	require.Equal(t, "weatherstack", s.providers[0].name())
+	require.Equal(t, "openweather", s.providers[1].name())
+	require.NotNil(t, s.obsCache)
+	require.NotNil(t, s.forecastCache)
+	require.NotNil(t, s.historyCache)
+	require.NotNil(t, s.alertsCache)
+	require.NotNil(t, s.alertLog)
+	require.NotNil(t, s.airCache)
+	require.NotNil(t, s.validator)
+	require.NotNil(t, s.outliers)
+	require.NotNil(t, s.metrics)
+	require.NotNil(t, s.health)
+}
+
+func TestService_Reload(t *testing.T) {
+	s := newTestService(&mockWeatherStackClient{}, &mockOpenWeatherClient{})
+
+	s.Reload(Config{
+		CacheExpiry:         time.Hour,
+		ForecastCacheExpiry: 2 * time.Hour,
+		ProviderOrder:       []string{"openweather"},
+		ProviderTimeout:     time.Second,
+	})
+
+	require.Equal(t, time.Hour, s.obsCache.ttl())
+	require.Equal(t, 2*time.Hour, s.forecastCache.ttl())
+	require.Equal(t, time.Hour, s.alertsCache.ttl())
+	require.Equal(t, time.Hour, s.airCache.ttl())
+
+	chain := s.chain()
+	require.Equal(t, []string{"openweather", "weatherstack"}, chainNames(chain.providers))
+	require.Equal(t, time.Second, chain.timeout)
+}
+
+func TestOrderProviders(t *testing.T) {
+	providers := []provider{&stubProvider{n: "a"}, &stubProvider{n: "b"}, &stubProvider{n: "c"}}
+
+	tests := []struct {
+		name  string
+		names []string
+		want  []string
+	}{
+		{name: "default order", want: []string{"a", "b", "c"}},
+		{name: "all listed", names: []string{"c", "a", "b"}, want: []string{"c", "a", "b"}},
+		{name: "some listed", names: []string{"c"}, want: []string{"c", "a", "b"}},
+		{name: "unknown and duplicate names", names: []string{"b", "x", "b"}, want: []string{"b", "a", "c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, chainNames(orderProviders(providers, tt.names)))
+		})
+	}
+}
+
+func TestCall_timeout(t *testing.T) {
+	get := func(ctx context.Context, _ provider) (*weather.Observation, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	_, err := call(context.Background(), time.Millisecond, &stubProvider{n: "a"}, get)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
 }
 
 func TestService_GetWeather(t *testing.T) {
@@ -37,16 +108,19 @@ func TestService_GetWeather(t *testing.T) {
 		name            string
 		primaryEnabled  bool
 		failOverEnabled bool
+		wantSource      string
 	}{
 		{
 			name:            "get weather from primary source",
 			primaryEnabled:  true,
 			failOverEnabled: false,
+			wantSource:      "weatherstack",
 		},
 		{
 			name:            "get weather from fail over source",
 			primaryEnabled:  false,
 			failOverEnabled: true,
+			wantSource:      "openweather",
 		},
 	}
 
@@ -67,11 +141,7 @@ func TestService_GetWeather(t *testing.T) {
 				failOver.wantErr = false
 			}
 
-			s := Service{
-				primary:   primary,
-				failOver:  failOver,
-				respCache: newValueCache[*GetWeatherResponse](100 * time.Millisecond),
-			}
+			s := newTestService(primary, failOver)
 
 			err := s.GetWeather(ctx)
 			require.NoError(t, err)
@@ -81,6 +151,11 @@ func TestService_GetWeather(t *testing.T) {
 			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
 			require.Equal(t, wantSpeed, resp.WindSpeed)
 			require.Equal(t, wantTemp, resp.TempDegrees)
+
+			require.Equal(t, tt.wantSource, rec.Header().Get(headerSource))
+			require.Equal(t, "false", rec.Header().Get(headerStale))
+			require.Equal(t, "0", rec.Header().Get(headerCacheAge))
+			require.NotEmpty(t, rec.Header().Get(headerFetchedAt))
 		})
 	}
 }
@@ -91,11 +166,7 @@ func TestService_GetWeather_useCache(t *testing.T) {
 	rec1, rec2 := httptest.NewRecorder(), httptest.NewRecorder()
 	ctx1, ctx2 := e.NewContext(req, rec1), e.NewContext(req, rec2)
 
-	s := Service{
-		primary:   &mockWeatherStackClient{},
-		failOver:  &mockOpenWeatherClient{},
-		respCache: newValueCache[*GetWeatherResponse](100 * time.Millisecond),
-	}
+	s := newTestService(&mockWeatherStackClient{}, &mockOpenWeatherClient{})
 
 	err := s.GetWeather(ctx1)
 	require.NoError(t, err)
@@ -111,35 +182,253 @@ func TestService_GetWeather_useCache(t *testing.T) {
 	require.Equal(t, http.StatusOK, rec2.Code)
 
 	// Return cached response without experiencing client errors
-	s.primary = &mockWeatherStackClient{wantErr: true}
-	s.failOver = &mockOpenWeatherClient{wantErr: true}
+	failing := newTestService(&mockWeatherStackClient{wantErr: true}, &mockOpenWeatherClient{wantErr: true})
+	s.providers, s.outliers = failing.providers, failing.outliers
 	var resp2 GetWeatherResponse
 	require.NoError(t, json.Unmarshal(rec2.Body.Bytes(), &resp2))
 	require.Equal(t, wantSpeed, resp2.WindSpeed)
 	require.Equal(t, wantTemp, resp2.TempDegrees)
 }
 
+func TestService_GetWeather_stale(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/v1/weather?city="+wantCity, nil)
+	s := newTestService(&mockWeatherStackClient{}, &mockOpenWeatherClient{})
+
+	rec := httptest.NewRecorder()
+	require.NoError(t, s.GetWeather(e.NewContext(req, rec)))
+	require.Equal(t, "false", rec.Header().Get(headerStale))
+
+	// Serve the expired cache value when every provider fails
+	time.Sleep(100*time.Millisecond + time.Millisecond)
+	failing := newTestService(&mockWeatherStackClient{wantErr: true}, &mockOpenWeatherClient{wantErr: true})
+	s.providers, s.outliers = failing.providers, failing.outliers
+
+	rec = httptest.NewRecorder()
+	require.NoError(t, s.GetWeather(e.NewContext(req, rec)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "weatherstack", rec.Header().Get(headerSource))
+	require.Equal(t, "true", rec.Header().Get(headerStale))
+
+	rec = httptest.NewRecorder()
+	require.NoError(t, s.GetExtendedWeather(e.NewContext(req, rec)))
+
+	var resp ExtendedWeatherResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.NotNil(t, resp.Provenance)
+	require.Equal(t, "weatherstack", resp.Provenance.Source)
+	require.True(t, resp.Provenance.Stale)
+	require.GreaterOrEqual(t, time.Since(resp.Provenance.FetchedAt), 100*time.Millisecond)
+}
+
 func TestService_GetWeather_unavailableError(t *testing.T) {
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/v1/weather?city=Sydney", nil)
 	rec := httptest.NewRecorder()
 	ctx := e.NewContext(req, rec)
 
-	s := Service{
-		primary:   &mockWeatherStackClient{wantErr: true},
-		failOver:  &mockOpenWeatherClient{wantErr: true},
-		respCache: newValueCache[*GetWeatherResponse](100 * time.Millisecond),
-	}
+	s := newTestService(&mockWeatherStackClient{wantErr: true}, &mockOpenWeatherClient{wantErr: true})
+
+	err := s.GetWeather(ctx)
+	require.EqualError(t, err, "code=503, message=Service Unavailable, internal=no provider could return weather")
+}
+
+func TestService_GetWeather_logsProviderErrors(t *testing.T) {
+	var buf bytes.Buffer
+	e := echo.New()
+	e.Use(middleware.RequestID(), logging.Middleware(logging.New(&buf, slog.LevelInfo)))
+	s := newTestService(&mockWeatherStackClient{wantErr: true}, &mockOpenWeatherClient{})
+	e.GET("/v1/weather", s.GetWeather)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/weather?city="+wantCity, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var got map[string]any
+	require.NoError(t, json.NewDecoder(&buf).Decode(&got))
+	require.Equal(t, "provider request failed", got[slog.MessageKey])
+	require.Equal(t, "WARN", got[slog.LevelKey])
+	require.Equal(t, rec.Header().Get(echo.HeaderXRequestID), got[logging.KeyRequestID])
+	require.Equal(t, "weatherstack", got["provider"])
+	require.Equal(t, "weather", got["operation"])
+	require.Equal(t, "some-error", got[logging.KeyError])
+}
+
+func TestService_GetWeather_implausible(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/v1/weather?city="+wantCity, nil)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+
+	s := newTestService(&implausibleWeatherStackClient{}, &mockOpenWeatherClient{})
+	require.NoError(t, s.GetWeather(ctx))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "openweather", rec.Header().Get(headerSource))
 
+	res, ok := s.obsCache.get()
+	require.True(t, ok)
+	require.Equal(t, "openweather", res.source)
+}
+
+func TestService_GetWeather_implausibleUnavailable(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/v1/weather?city="+wantCity, nil)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+
+	s := newTestService(&implausibleWeatherStackClient{}, &mockOpenWeatherClient{wantErr: true})
 	err := s.GetWeather(ctx)
-	require.EqualError(t, err, "code=503, message=Service Unavailable")
+
+	var chainErr *chainError
+	require.ErrorAs(t, err, &chainErr)
+	require.Equal(t, []ProviderFailure{
+		{Provider: "weatherstack", Error: "implausible observation: temperature 400 outside [-90, 60]"},
+		{Provider: "openweather", Error: "some-error"},
+	}, chainErr.failures)
+
+	_, ok := s.obsCache.get()
+	require.False(t, ok)
+}
+
+func TestService_GetExtendedWeather(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/v2/weather?city=Sydney", nil)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+
+	s := newTestService(&mockWeatherStackClient{}, &mockOpenWeatherClient{})
+
+	err := s.GetExtendedWeather(ctx)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp ExtendedWeatherResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, float64(wantSpeed), resp.WindSpeed)
+	require.Equal(t, float64(wantTemp), resp.TempDegrees)
+
+	// Fields not reported by the provider are null rather than zero
+	require.Contains(t, rec.Body.String(), `"wind_gust_kmh":null`)
+	require.Nil(t, resp.WindGust)
+	require.Equal(t, string(weather.ConditionUnknown), resp.Condition)
+	require.Equal(t, "unknown", resp.Icon)
+}
+
+func TestService_GetExtendedWeather_failOver(t *testing.T) {
+	e := echo.New()
+	s := newTestService(&mockWeatherStackClient{wantErr: true}, &mockOpenWeatherClient{})
+
+	// v1 serves OpenWeather's wind speed in metres per second as it always has,
+	// while v2 converts it to kilometres per hour
+	rec := httptest.NewRecorder()
+	require.NoError(t, s.GetWeather(e.NewContext(httptest.NewRequest(http.MethodGet, "/v1/weather?city=Sydney", nil), rec)))
+	var v1Resp GetWeatherResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &v1Resp))
+	require.Equal(t, wantSpeed, v1Resp.WindSpeed)
+
+	rec = httptest.NewRecorder()
+	require.NoError(t, s.GetExtendedWeather(e.NewContext(httptest.NewRequest(http.MethodGet, "/v2/weather?city=Sydney", nil), rec)))
+	var v2Resp ExtendedWeatherResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &v2Resp))
+	require.InDelta(t, wantSpeed*3.6, v2Resp.WindSpeed, 0.001)
+}
+
+func TestNewExtendedWeatherResponse_comfort(t *testing.T) {
+	tests := []struct {
+		name          string
+		obs           weather.Observation
+		wantFeelsLike bool
+		wantDewPoint  bool
+		wantHeatIndex bool
+		wantWindChill bool
+	}{
+		{
+			name:          "hot and humid",
+			obs:           weather.Observation{Temperature: 35, Humidity: ptr(60), WindSpeed: 10},
+			wantFeelsLike: true,
+			wantDewPoint:  true,
+			wantHeatIndex: true,
+		},
+		{
+			name:          "cold and windy",
+			obs:           weather.Observation{Temperature: 5, Humidity: ptr(60), WindSpeed: 30},
+			wantFeelsLike: true,
+			wantDewPoint:  true,
+			wantWindChill: true,
+		},
+		{
+			name: "humidity not reported",
+			obs:  weather.Observation{Temperature: 20, WindSpeed: 10},
+		},
+		{
+			name:          "zero humidity",
+			obs:           weather.Observation{Temperature: 20, Humidity: ptr(0), WindSpeed: 10},
+			wantFeelsLike: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := newExtendedWeatherResponse(&tt.obs)
+			require.Equal(t, tt.wantFeelsLike, resp.FeelsLikeDegrees != nil)
+			require.Equal(t, tt.wantDewPoint, resp.DewPointDegrees != nil)
+			require.Equal(t, tt.wantHeatIndex, resp.HeatIndexDegrees != nil)
+			require.Equal(t, tt.wantWindChill, resp.WindChillDegrees != nil)
+
+			_, err := json.Marshal(resp)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestNewExtendedWeatherResponse_wind(t *testing.T) {
+	resp := newExtendedWeatherResponse(&weather.Observation{
+		WindSpeed:  30,
+		WindDegree: ptr(22),
+		WindGust:   ptr(65.0),
+	})
+	require.Equal(t, 5, resp.BeaufortForce)
+	require.Equal(t, "Fresh breeze", resp.BeaufortDesc)
+	require.Equal(t, ptr("NNE"), resp.WindDirection)
+	require.Equal(t, ptr(8), resp.GustBeaufort)
+
+	// Direction and gusts are null when not reported
+	resp = newExtendedWeatherResponse(&weather.Observation{WindSpeed: 0})
+	require.Equal(t, 0, resp.BeaufortForce)
+	require.Nil(t, resp.WindDirection)
+	require.Nil(t, resp.GustBeaufort)
+}
+
+func newTestService(primary WeatherStackClient, failOver OpenWeatherClient) *Service {
+	providers := []provider{
+		&weatherStackProvider{client: primary},
+		&openWeatherProvider{client: failOver},
+	}
+	validator := weather.NewValidator(weather.Limits{}, nil)
+
+	s := &Service{
+		providers:     providers,
+		obsCache:      newValueCache[result[weather.Observation]](100 * time.Millisecond),
+		forecastCache: newValueCache[result[weather.Forecast]](100 * time.Millisecond),
+		historyCache:  newMapCache[string, result[weather.Observation]](historyCacheSize),
+		alertsCache:   newValueCache[result[[]weather.Alert]](100 * time.Millisecond),
+		alertLog:      newAlertLog(),
+		airCache:      newValueCache[result[weather.AirQuality]](100 * time.Millisecond),
+		validator:     validator,
+		outliers:      newOutlierMonitor(OutlierConfig{}, providers, []weather.Location{location}, validator),
+		metrics:       newMetrics(),
+		health:        newHealthTracker(HealthConfig{}),
+	}
+	s.registerCacheAges()
+
+	return s
 }
 
 type mockWeatherStackClient struct {
 	wantErr bool
 }
 
-func (c *mockWeatherStackClient) GetWeather(_ string) (*weather.WeatherStackResponse, error) {
+func (c *mockWeatherStackClient) GetWeather(_ context.Context, _ string) (*weather.WeatherStackResponse, error) {
 	if c.wantErr {
 		return nil, errors.New("some-error")
 	}
@@ -151,11 +440,25 @@ func (c *mockWeatherStackClient) GetWeather(_ string) (*weather.WeatherStackResp
 	}, nil
 }
 
+// implausibleWeatherStackClient reports a temperature no city has recorded.
+type implausibleWeatherStackClient struct {
+	mockWeatherStackClient
+}
+
+func (c *implausibleWeatherStackClient) GetWeather(_ context.Context, _ string) (*weather.WeatherStackResponse, error) {
+	return &weather.WeatherStackResponse{
+		Current: weather.WeatherStackCurrent{
+			WindSpeed:   wantSpeed,
+			Temperature: 400,
+		},
+	}, nil
+}
+
 type mockOpenWeatherClient struct {
 	wantErr bool
 }
 
-func (c *mockOpenWeatherClient) GetWeather(_ string) (*weather.OpenWeatherResponse, error) {
+func (c *mockOpenWeatherClient) GetWeather(_ context.Context, _ string) (*weather.OpenWeatherResponse, error) {
 	if c.wantErr {
 		return nil, errors.New("some-error")
 	}
@@ -168,3 +471,100 @@ func (c *mockOpenWeatherClient) GetWeather(_ string) (*weather.OpenWeatherRespon
 		},
 	}, nil
 }
+
+func (c *mockWeatherStackClient) GetForecast(_ context.Context, _ string) (*weather.WeatherStackForecastResponse, error) {
+	if c.wantErr {
+		return nil, errors.New("some-error")
+	}
+	date := time.Now().In(location.Zone).Format("2006-01-02")
+	return &weather.WeatherStackForecastResponse{
+		Days: map[string]weather.WeatherStackForecastDay{
+			date: {
+				Date:    date,
+				MinTemp: wantMinTemp,
+				MaxTemp: wantTemp,
+				Hourly: []weather.WeatherStackHourly{
+					{Time: "2300", Temperature: wantTemp, WindSpeed: wantSpeed},
+				},
+			},
+		},
+	}, nil
+}
+
+func (c *mockOpenWeatherClient) GetForecast(_ context.Context, _ float64, _ float64) (*weather.OpenWeatherOneCallResponse, error) {
+	if c.wantErr {
+		return nil, errors.New("some-error")
+	}
+	now := time.Now()
+	return &weather.OpenWeatherOneCallResponse{
+		Hourly: []weather.OpenWeatherHourly{
+			{Dt: now.Add(time.Hour).Unix(), Temp: wantTemp, WindSpeed: wantSpeed / 3.6},
+		},
+		Daily: []weather.OpenWeatherDaily{
+			{Dt: now.Unix(), Temp: weather.OpenWeatherDailyTemp{Min: wantMinTemp, Max: wantTemp}},
+		},
+	}, nil
+}
+
+func (c *mockWeatherStackClient) GetHistory(_ context.Context, _ string, t time.Time) (*weather.WeatherStackHistoryResponse, error) {
+	if c.wantErr {
+		return nil, errors.New("some-error")
+	}
+	date := t.In(location.Zone).Format("2006-01-02")
+	return &weather.WeatherStackHistoryResponse{
+		Days: map[string]weather.WeatherStackForecastDay{
+			date: {
+				Date: date,
+				Hourly: []weather.WeatherStackHourly{
+					{Time: "1200", Temperature: wantTemp, WindSpeed: wantSpeed},
+				},
+			},
+		},
+	}, nil
+}
+
+func (c *mockOpenWeatherClient) GetHistory(_ context.Context, _ float64, _ float64, t time.Time) (*weather.OpenWeatherTimeMachineResponse, error) {
+	if c.wantErr {
+		return nil, errors.New("some-error")
+	}
+	return &weather.OpenWeatherTimeMachineResponse{
+		Data: []weather.OpenWeatherHourly{
+			{Dt: t.Unix(), Temp: wantTemp, WindSpeed: wantSpeed / 3.6},
+		},
+	}, nil
+}
+
+func (c *mockOpenWeatherClient) GetAlerts(_ context.Context, _ float64, _ float64) (*weather.OpenWeatherOneCallResponse, error) {
+	if c.wantErr {
+		return nil, errors.New("some-error")
+	}
+	now := time.Now()
+	return &weather.OpenWeatherOneCallResponse{
+		Warnings: []weather.OpenWeatherAlert{
+			{
+				SenderName: wantAlertSource,
+				Event:      wantAlertHeadline,
+				Start:      now.Add(-time.Hour).Unix(),
+				End:        now.Add(time.Hour).Unix(),
+			},
+		},
+	}, nil
+}
+
+func (c *mockOpenWeatherClient) GetAirPollution(_ context.Context, _ float64, _ float64) (*weather.OpenWeatherAirPollutionResponse, error) {
+	if c.wantErr {
+		return nil, errors.New("some-error")
+	}
+	return &weather.OpenWeatherAirPollutionResponse{
+		List: []weather.OpenWeatherAirPollution{
+			{
+				Dt:         time.Now().Unix(),
+				Components: weather.OpenWeatherAirPollutionComponents{PM25: ptr(wantPM25)},
+			},
+		},
+	}, nil
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/joshjon/sydneyweather/internal/logging"
+)
+
+const tracerName = "github.com/joshjon/sydneyweather/internal/api/v1"
+
+// Span attribute keys.
+const (
+	attrCacheResult      = attribute.Key("cache.result")
+	attrProvider         = attribute.Key("weather.provider")
+	attrOperation        = attribute.Key("weather.operation")
+	attrOutcome          = attribute.Key("weather.outcome")
+	attrFailover         = attribute.Key("weather.failover")
+	attrPreviousFailures = attribute.Key("weather.previous_failures")
+)
+
+// startCacheSpan starts the span of a cache lookup, which is the parent of the
+// provider spans when the lookup misses.
+func startCacheSpan(ctx context.Context, desc string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, "cache "+operation(desc),
+		trace.WithAttributes(attrOperation.String(operation(desc))),
+	)
+}
+
+func setCacheResult(span trace.Span, result string) {
+	span.SetAttributes(attrCacheResult.String(result))
+}
+
+// startProviderSpan starts the span of a provider call. Calls made after an
+// earlier provider in the chain failed are marked as fail overs.
+func startProviderSpan(ctx context.Context, p provider, desc string, failures int) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, p.name()+" "+operation(desc),
+		trace.WithAttributes(
+			attrProvider.String(p.name()),
+			attrOperation.String(operation(desc)),
+			attrFailover.Bool(failures > 0),
+			attrPreviousFailures.Int(failures),
+		),
+	)
+}
+
+// endProviderSpan records the outcome of a provider call and ends its span.
+// Errors are redacted since client errors can include API keys.
+func endProviderSpan(span trace.Span, err error) {
+	span.SetAttributes(attrOutcome.String(providerOutcome(err)))
+	if err != nil {
+		msg := logging.Redact(err.Error())
+		span.AddEvent(semconv.ExceptionEventName, trace.WithAttributes(semconv.ExceptionMessageKey.String(msg)))
+		span.SetStatus(codes.Error, msg)
+	}
+	span.End()
+}
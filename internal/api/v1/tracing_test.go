@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestService_tracing(t *testing.T) {
+	recorder := recordSpans(t)
+
+	s := newTestService(&mockWeatherStackClient{wantErr: true}, &mockOpenWeatherClient{})
+	_, err := s.currentObservation(context.Background())
+	require.NoError(t, err)
+	_, err = s.currentObservation(context.Background())
+	require.NoError(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 4)
+
+	weatherStack, openWeather, miss, hit := spans[0], spans[1], spans[2], spans[3]
+
+	require.Equal(t, "weatherstack weather", weatherStack.Name())
+	require.Equal(t, codes.Error, weatherStack.Status().Code)
+	require.Contains(t, weatherStack.Attributes(), attrOutcome.String(outcomeError))
+	require.Contains(t, weatherStack.Attributes(), attrFailover.Bool(false))
+	require.Len(t, weatherStack.Events(), 1)
+
+	require.Equal(t, "openweather weather", openWeather.Name())
+	require.Equal(t, codes.Unset, openWeather.Status().Code)
+	require.Contains(t, openWeather.Attributes(), attrOutcome.String(outcomeSuccess))
+	require.Contains(t, openWeather.Attributes(), attrFailover.Bool(true))
+	require.Contains(t, openWeather.Attributes(), attrPreviousFailures.Int(1))
+
+	require.Equal(t, "cache weather", miss.Name())
+	require.Contains(t, miss.Attributes(), attrCacheResult.String(cacheMiss))
+	require.Equal(t, miss.SpanContext().SpanID(), weatherStack.Parent().SpanID())
+	require.Equal(t, miss.SpanContext().SpanID(), openWeather.Parent().SpanID())
+
+	require.Equal(t, "cache weather", hit.Name())
+	require.Contains(t, hit.Attributes(), attrCacheResult.String(cacheHit))
+}
+
+func TestEndProviderSpan_redactsError(t *testing.T) {
+	recorder := recordSpans(t)
+
+	_, span := otel.Tracer(tracerName).Start(context.Background(), "some-span")
+	endProviderSpan(span, &someURLError{})
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	require.Equal(t, `Get "http://some-host/current?access_key=REDACTED": EOF`, spans[0].Status().Description)
+	require.Contains(t, spans[0].Events()[0].Attributes,
+		attribute.String("exception.message", `Get "http://some-host/current?access_key=REDACTED": EOF`))
+}
+
+type someURLError struct{}
+
+func (e *someURLError) Error() string {
+	return `Get "http://some-host/current?access_key=secret": EOF`
+}
+
+// recordSpans sets a global tracer provider that records spans for the
+// duration of the test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return recorder
+}
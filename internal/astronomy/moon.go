@@ -0,0 +1,69 @@
+package astronomy
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// synodicMonth is the mean length in days of a lunar cycle.
+	synodicMonth = 29.530588853
+	// referenceNewMoon is the Julian day of the new moon on 6 January 2000 at
+	// 18:14 UTC.
+	referenceNewMoon = 2451550.1
+)
+
+// MoonPhaseName is the conventional name of one of the eight lunar phases.
+type MoonPhaseName string
+
+const (
+	NewMoon        MoonPhaseName = "new_moon"
+	WaxingCrescent MoonPhaseName = "waxing_crescent"
+	FirstQuarter   MoonPhaseName = "first_quarter"
+	WaxingGibbous  MoonPhaseName = "waxing_gibbous"
+	FullMoon       MoonPhaseName = "full_moon"
+	WaningGibbous  MoonPhaseName = "waning_gibbous"
+	LastQuarter    MoonPhaseName = "last_quarter"
+	WaningCrescent MoonPhaseName = "waning_crescent"
+)
+
+// phaseNames are ordered by the eighth of the lunar cycle they are centred on.
+var phaseNames = []MoonPhaseName{
+	NewMoon,
+	WaxingCrescent,
+	FirstQuarter,
+	WaxingGibbous,
+	FullMoon,
+	WaningGibbous,
+	LastQuarter,
+	WaningCrescent,
+}
+
+// MoonPhase describes the moon at an instant.
+type MoonPhase struct {
+	Name MoonPhaseName
+	// Phase is the fraction of the lunar cycle elapsed, where 0 is a new moon
+	// and 0.5 is a full moon.
+	Phase float64
+	// Age is the number of days since the last new moon.
+	Age float64
+	// Illumination is the fraction of the moon's disc that is lit.
+	Illumination float64
+}
+
+// Moon computes the phase of the moon at t using the mean lunar cycle, which is
+// accurate to within about a day of the true phase.
+func Moon(t time.Time) MoonPhase {
+	age := math.Mod(julianDay(t)-referenceNewMoon, synodicMonth)
+	if age < 0 {
+		age += synodicMonth
+	}
+
+	phase := age / synodicMonth
+	return MoonPhase{
+		Name:         phaseNames[int(math.Floor(phase*8+0.5))%8],
+		Phase:        phase,
+		Age:          age,
+		Illumination: (1 - math.Cos(2*math.Pi*phase)) / 2,
+	}
+}
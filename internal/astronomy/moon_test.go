@@ -0,0 +1,41 @@
+package astronomy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMoon(t *testing.T) {
+	// Reference phases from https://aa.usno.navy.mil/data/MoonPhases
+	tests := []struct {
+		name     string
+		time     time.Time
+		wantName MoonPhaseName
+	}{
+		{name: "new moon", time: time.Date(2022, 6, 29, 2, 52, 0, 0, time.UTC), wantName: NewMoon},
+		{name: "first quarter", time: time.Date(2022, 7, 7, 2, 14, 0, 0, time.UTC), wantName: FirstQuarter},
+		{name: "full moon", time: time.Date(2022, 6, 14, 11, 52, 0, 0, time.UTC), wantName: FullMoon},
+		{name: "last quarter", time: time.Date(2022, 6, 21, 3, 11, 0, 0, time.UTC), wantName: LastQuarter},
+		{name: "waxing crescent", time: time.Date(2022, 7, 3, 0, 0, 0, 0, time.UTC), wantName: WaxingCrescent},
+		{name: "waning gibbous", time: time.Date(2022, 6, 17, 12, 0, 0, 0, time.UTC), wantName: WaningGibbous},
+		{name: "before reference", time: time.Date(1999, 12, 22, 17, 31, 0, 0, time.UTC), wantName: FullMoon},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.wantName, Moon(tt.time).Name)
+		})
+	}
+}
+
+func TestMoon_illumination(t *testing.T) {
+	full := Moon(time.Date(2022, 6, 14, 11, 52, 0, 0, time.UTC))
+	require.InDelta(t, 1, full.Illumination, 0.01)
+	require.InDelta(t, 0.5, full.Phase, 0.02)
+
+	newMoon := Moon(time.Date(2022, 6, 29, 2, 52, 0, 0, time.UTC))
+	require.InDelta(t, 0, newMoon.Illumination, 0.01)
+	require.Less(t, newMoon.Age, 1.0)
+}
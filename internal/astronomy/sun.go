@@ -0,0 +1,171 @@
+// Package astronomy computes the position of the sun and moon locally, which
+// avoids an upstream call and works offline.
+package astronomy
+
+import (
+	"math"
+	"time"
+)
+
+// Zenith angles in degrees at which the sun is considered to rise or set.
+const (
+	// sunriseZenith accounts for atmospheric refraction and the sun's radius.
+	sunriseZenith = 90.833
+	// civilTwilightZenith is the sun's centre six degrees below the horizon.
+	civilTwilightZenith = 96
+)
+
+// julianDayUnixEpoch is the Julian day of the unix epoch.
+const julianDayUnixEpoch = 2440587.5
+
+// SunTimes are the times of solar events on a local date. Events that do not
+// occur, e.g. sunrise during a polar night, are nil.
+type SunTimes struct {
+	SolarNoon time.Time
+	Sunrise   *time.Time
+	Sunset    *time.Time
+	CivilDawn *time.Time
+	CivilDusk *time.Time
+	DayLength time.Duration
+}
+
+// Sun computes the times of solar events for the local date of date at the
+// specified coordinates using the NOAA solar calculator algorithms. Times are
+// accurate to within a minute or so for latitudes between +/- 72 degrees. See
+// https://gml.noaa.gov/grad/solcalc/calcdetails.html
+func Sun(date time.Time, lat float64, lon float64) SunTimes {
+	zone := date.Location()
+	y, m, d := date.Date()
+
+	// Solar parameters change little over a day, so they are computed once at
+	// local noon
+	jc := julianCentury(julianDay(time.Date(y, m, d, 12, 0, 0, 0, zone)))
+	declination, eqTime := solarDeclination(jc), equationOfTime(jc)
+
+	// Minutes after midnight UTC on the date. Using the local date means the
+	// result is on the expected day for zones that roughly follow longitude.
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	at := func(minutes float64) time.Time {
+		return midnight.Add(time.Duration(minutes * float64(time.Minute))).Truncate(time.Second).In(zone)
+	}
+
+	noonMinutes := 720 - 4*lon - eqTime
+	event := func(zenith float64, sign float64) *time.Time {
+		ha, ok := hourAngle(lat, declination, zenith)
+		if !ok {
+			return nil
+		}
+		t := at(noonMinutes + sign*4*ha)
+		return &t
+	}
+
+	sun := SunTimes{
+		SolarNoon: at(noonMinutes),
+		Sunrise:   event(sunriseZenith, -1),
+		Sunset:    event(sunriseZenith, 1),
+		CivilDawn: event(civilTwilightZenith, -1),
+		CivilDusk: event(civilTwilightZenith, 1),
+	}
+
+	switch cos := cosHourAngle(lat, declination, sunriseZenith); {
+	case cos < -1:
+		sun.DayLength = 24 * time.Hour // Polar day
+	case cos > 1:
+		sun.DayLength = 0 // Polar night
+	default:
+		sun.DayLength = sun.Sunset.Sub(*sun.Sunrise)
+	}
+
+	return sun
+}
+
+// hourAngle returns the hour angle in degrees of the sun at the zenith angle.
+// False is returned if the sun never reaches the angle on the day.
+func hourAngle(lat float64, declination float64, zenith float64) (float64, bool) {
+	cos := cosHourAngle(lat, declination, zenith)
+	if cos < -1 || cos > 1 {
+		return 0, false
+	}
+	return degrees(math.Acos(cos)), true
+}
+
+// cosHourAngle returns the cosine of the hour angle. Values below -1 mean the
+// sun is always above the zenith angle and values above 1 mean it never is.
+func cosHourAngle(lat float64, declination float64, zenith float64) float64 {
+	latRad, declRad := radians(lat), radians(declination)
+	return math.Cos(radians(zenith))/(math.Cos(latRad)*math.Cos(declRad)) - math.Tan(latRad)*math.Tan(declRad)
+}
+
+// solarDeclination returns the sun's declination in degrees.
+func solarDeclination(jc float64) float64 {
+	return degrees(math.Asin(math.Sin(radians(obliquityCorrection(jc))) * math.Sin(radians(sunApparentLongitude(jc)))))
+}
+
+// equationOfTime returns the difference between apparent and mean solar time
+// in minutes.
+func equationOfTime(jc float64) float64 {
+	epsilon := obliquityCorrection(jc)
+	l0 := radians(geomMeanLongSun(jc))
+	e := eccentricityEarthOrbit(jc)
+	m := radians(geomMeanAnomalySun(jc))
+
+	y := math.Pow(math.Tan(radians(epsilon)/2), 2)
+
+	eq := y*math.Sin(2*l0) -
+		2*e*math.Sin(m) +
+		4*e*y*math.Sin(m)*math.Cos(2*l0) -
+		0.5*y*y*math.Sin(4*l0) -
+		1.25*e*e*math.Sin(2*m)
+
+	return 4 * degrees(eq)
+}
+
+func geomMeanLongSun(jc float64) float64 {
+	return math.Mod(280.46646+jc*(36000.76983+jc*0.0003032), 360)
+}
+
+func geomMeanAnomalySun(jc float64) float64 {
+	return 357.52911 + jc*(35999.05029-0.0001537*jc)
+}
+
+func eccentricityEarthOrbit(jc float64) float64 {
+	return 0.016708634 - jc*(0.000042037+0.0000001267*jc)
+}
+
+func sunEquationOfCentre(jc float64) float64 {
+	m := radians(geomMeanAnomalySun(jc))
+	return math.Sin(m)*(1.914602-jc*(0.004817+0.000014*jc)) +
+		math.Sin(2*m)*(0.019993-0.000101*jc) +
+		math.Sin(3*m)*0.000289
+}
+
+func sunApparentLongitude(jc float64) float64 {
+	trueLong := geomMeanLongSun(jc) + sunEquationOfCentre(jc)
+	return trueLong - 0.00569 - 0.00478*math.Sin(radians(125.04-1934.136*jc))
+}
+
+func meanObliquityOfEcliptic(jc float64) float64 {
+	seconds := 21.448 - jc*(46.815+jc*(0.00059-jc*0.001813))
+	return 23 + (26+seconds/60)/60
+}
+
+func obliquityCorrection(jc float64) float64 {
+	return meanObliquityOfEcliptic(jc) + 0.00256*math.Cos(radians(125.04-1934.136*jc))
+}
+
+func julianDay(t time.Time) float64 {
+	return julianDayUnixEpoch + float64(t.UnixNano())/float64(24*time.Hour)
+}
+
+// julianCentury returns the Julian centuries since J2000.0.
+func julianCentury(jd float64) float64 {
+	return (jd - 2451545) / 36525
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func degrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
@@ -0,0 +1,103 @@
+package astronomy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	sydneyLat = -33.8688
+	sydneyLon = 151.2093
+)
+
+func TestSun(t *testing.T) {
+	sydney, err := time.LoadLocation("Australia/Sydney")
+	require.NoError(t, err)
+	london, err := time.LoadLocation("Europe/London")
+	require.NoError(t, err)
+
+	// Reference values from the NOAA solar calculator and timeanddate.com
+	tests := []struct {
+		name          string
+		date          time.Time
+		lat           float64
+		lon           float64
+		wantSunrise   string
+		wantSunset    string
+		wantSolarNoon string
+		wantCivilDawn string
+		wantCivilDusk string
+	}{
+		{
+			name:          "sydney winter solstice",
+			date:          time.Date(2022, 6, 21, 0, 0, 0, 0, sydney),
+			lat:           sydneyLat,
+			lon:           sydneyLon,
+			wantSunrise:   "07:00",
+			wantSunset:    "16:53",
+			wantSolarNoon: "11:57",
+			wantCivilDawn: "06:33",
+			wantCivilDusk: "17:21",
+		},
+		{
+			name:          "sydney summer solstice",
+			date:          time.Date(2022, 12, 21, 0, 0, 0, 0, sydney),
+			lat:           sydneyLat,
+			lon:           sydneyLon,
+			wantSunrise:   "05:41",
+			wantSunset:    "20:05",
+			wantSolarNoon: "12:53",
+			wantCivilDawn: "05:12",
+			wantCivilDusk: "20:34",
+		},
+		{
+			name:          "london spring equinox",
+			date:          time.Date(2022, 3, 20, 0, 0, 0, 0, london),
+			lat:           51.5072,
+			lon:           -0.1276,
+			wantSunrise:   "06:03",
+			wantSunset:    "18:14",
+			wantSolarNoon: "12:08",
+			wantCivilDawn: "05:31",
+			wantCivilDusk: "18:46",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sun := Sun(tt.date, tt.lat, tt.lon)
+			requireClock(t, tt.date, tt.wantSolarNoon, sun.SolarNoon)
+			requireClock(t, tt.date, tt.wantSunrise, *sun.Sunrise)
+			requireClock(t, tt.date, tt.wantSunset, *sun.Sunset)
+			requireClock(t, tt.date, tt.wantCivilDawn, *sun.CivilDawn)
+			requireClock(t, tt.date, tt.wantCivilDusk, *sun.CivilDusk)
+			require.Equal(t, sun.Sunset.Sub(*sun.Sunrise), sun.DayLength)
+		})
+	}
+}
+
+func TestSun_polar(t *testing.T) {
+	// Tromsø, Norway
+	day := Sun(time.Date(2022, 6, 21, 0, 0, 0, 0, time.UTC), 69.6492, 18.9553)
+	require.Nil(t, day.Sunrise)
+	require.Nil(t, day.Sunset)
+	require.Equal(t, 24*time.Hour, day.DayLength)
+
+	night := Sun(time.Date(2022, 12, 21, 0, 0, 0, 0, time.UTC), 69.6492, 18.9553)
+	require.Nil(t, night.Sunrise)
+	require.Nil(t, night.Sunset)
+	require.NotNil(t, night.CivilDawn) // The sun still rises above -6 degrees
+	require.Zero(t, night.DayLength)
+}
+
+// requireClock asserts that got is within two minutes of the local clock time
+// on date.
+func requireClock(t *testing.T, date time.Time, want string, got time.Time) {
+	t.Helper()
+	clock, err := time.Parse("15:04", want)
+	require.NoError(t, err)
+	wantTime := time.Date(date.Year(), date.Month(), date.Day(), clock.Hour(), clock.Minute(), 0, 0, date.Location())
+	require.WithinDuration(t, wantTime, got, 2*time.Minute, "want %s got %s", want, got.Format("15:04:05"))
+}
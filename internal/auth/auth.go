@@ -0,0 +1,285 @@
+// Package auth authenticates clients of the service by API key and enforces
+// their rate limits and daily quotas.
+package auth
+
+import (
+	"crypto/sha256"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/time/rate"
+
+	"github.com/joshjon/sydneyweather/internal/logging"
+)
+
+// DefaultHeader is the request header that carries the API key when no other
+// header is configured.
+const DefaultHeader = "X-API-Key"
+
+// clientKey is the echo context key of the authenticated Client.
+const clientKey = "auth.client"
+
+// Client is a consumer of the service identified by its API key.
+type Client struct {
+	Name       string
+	Key        string
+	RateLimit  float64 // Requests per second, 0 is no limit
+	Burst      int     // Requests allowed at once, defaults to the rate rounded up
+	DailyQuota int     // Requests per UTC day, 0 is no quota
+	Admin      bool    // Can read the usage of every client
+}
+
+// Config configures authentication. Requests are let through without an API
+// key when it is disabled.
+type Config struct {
+	Enabled bool
+	Header  string
+	Clients []Client
+}
+
+type UsageResponse struct {
+	Clients []ClientUsageResponse `json:"clients"`
+}
+
+// ClientUsageResponse counts a client's requests since the service started and
+// today (UTC), which count towards its daily quota. The quota and remaining
+// requests are null when the client has no quota.
+type ClientUsageResponse struct {
+	Name           string     `json:"name"`
+	Requests       int64      `json:"requests"`
+	RateLimited    int64      `json:"rate_limited"`
+	QuotaExceeded  int64      `json:"quota_exceeded"`
+	DailyQuota     *int       `json:"daily_quota"`
+	QuotaUsed      int        `json:"quota_used"`
+	QuotaRemaining *int       `json:"quota_remaining"`
+	LastSeen       *time.Time `json:"last_seen"`
+}
+
+// Authenticator checks the API key of each request and counts each client's
+// usage. Its config can be updated while requests are served.
+type Authenticator struct {
+	now func() time.Time
+
+	mu      sync.RWMutex
+	enabled bool
+	header  string
+	clients map[[sha256.Size]byte]*client // By key hash, so lookups do not leak keys through timing
+}
+
+// client is the state of a client. Usage is kept when the config is updated.
+type client struct {
+	Client
+	limiter *rate.Limiter // Nil when there is no rate limit
+
+	mu            sync.Mutex
+	requests      int64
+	rateLimited   int64
+	quotaExceeded int64
+	day           time.Time // UTC day that quotaUsed counts
+	quotaUsed     int
+	lastSeen      time.Time
+}
+
+// NewAuthenticator creates an authenticator of the configured clients.
+func NewAuthenticator(cfg Config) *Authenticator {
+	a := &Authenticator{now: time.Now}
+	a.Update(cfg)
+	return a
+}
+
+// Update replaces the config. Clients are matched to existing clients by name,
+// keeping their usage and rate limit state.
+func (a *Authenticator) Update(cfg Config) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	existing := make(map[string]*client, len(a.clients))
+	for _, c := range a.clients {
+		existing[c.Name] = c
+	}
+
+	clients := make(map[[sha256.Size]byte]*client, len(cfg.Clients))
+	for _, cc := range cfg.Clients {
+		c, ok := existing[cc.Name]
+		if !ok {
+			c = &client{}
+		}
+		c.update(cc)
+		clients[sha256.Sum256([]byte(cc.Key))] = c
+	}
+
+	a.enabled = cfg.Enabled
+	a.header = cfg.Header
+	if a.header == "" {
+		a.header = DefaultHeader
+	}
+	a.clients = clients
+}
+
+// Middleware authenticates requests by the API key in the configured header and
+// rejects them with 429 Too Many Requests and a Retry-After header when the
+// client is over its rate limit or daily quota. The client's name is added to
+// the request logger and cacheable responses to authenticated requests are made
+// private.
+func (a *Authenticator) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			// Clients are replaced rather than modified on update
+			a.mu.RLock()
+			enabled, header, clients := a.enabled, a.header, a.clients
+			a.mu.RUnlock()
+
+			if !enabled {
+				return next(ctx)
+			}
+
+			key := ctx.Request().Header.Get(header)
+			if key == "" {
+				return echo.NewHTTPError(http.StatusUnauthorized, "missing API key in header '"+header+"'")
+			}
+			c, ok := clients[sha256.Sum256([]byte(key))]
+			if !ok {
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid API key")
+			}
+
+			cfg, retryAfter, msg := c.allow(a.now())
+			if retryAfter > 0 {
+				ctx.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				return echo.NewHTTPError(http.StatusTooManyRequests, msg)
+			}
+
+			ctx.Set(clientKey, cfg)
+			req := ctx.Request()
+			logger := logging.FromContext(req.Context()).With(logging.KeyClient, cfg.Name)
+			ctx.SetRequest(req.WithContext(logging.WithLogger(req.Context(), logger)))
+
+			res := ctx.Response()
+			res.Before(func() { makePrivate(res.Header()) })
+			return next(ctx)
+		}
+	}
+}
+
+// RequireAdmin rejects requests that were not authenticated as an admin client
+// with 403 Forbidden. It must run after Middleware.
+func (a *Authenticator) RequireAdmin() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			if c, ok := ctx.Get(clientKey).(Client); !ok || !c.Admin {
+				return echo.NewHTTPError(http.StatusForbidden, "an admin API key is required")
+			}
+			return next(ctx)
+		}
+	}
+}
+
+// GetUsage returns the usage of every client ordered by name.
+func (a *Authenticator) GetUsage(ctx echo.Context) error {
+	a.mu.RLock()
+	resp := &UsageResponse{Clients: make([]ClientUsageResponse, 0, len(a.clients))}
+	for _, c := range a.clients {
+		resp.Clients = append(resp.Clients, c.usage(a.now()))
+	}
+	a.mu.RUnlock()
+
+	sort.Slice(resp.Clients, func(i, j int) bool { return resp.Clients[i].Name < resp.Clients[j].Name })
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// makePrivate stops shared caches such as CDNs from storing a response to an
+// authenticated request, which they would otherwise replay to clients without
+// a key.
+func makePrivate(h http.Header) {
+	if cc := h.Get(echo.HeaderCacheControl); strings.HasPrefix(cc, "public") {
+		h.Set(echo.HeaderCacheControl, "private"+strings.TrimPrefix(cc, "public"))
+	}
+}
+
+func (c *client) update(cfg Client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Client = cfg
+	if cfg.RateLimit <= 0 {
+		c.limiter = nil
+		return
+	}
+
+	burst := cfg.Burst
+	if burst <= 0 {
+		burst = int(math.Ceil(cfg.RateLimit))
+	}
+
+	if c.limiter == nil {
+		c.limiter = rate.NewLimiter(rate.Limit(cfg.RateLimit), burst)
+		return
+	}
+	c.limiter.SetLimit(rate.Limit(cfg.RateLimit))
+	c.limiter.SetBurst(burst)
+}
+
+// allow counts a request made at now. It returns the client's config, along
+// with how long to wait before retrying and why when the request is over the
+// daily quota or the rate limit.
+func (c *client) allow(now time.Time) (Client, time.Duration, string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lastSeen = now
+	if day := utcDay(now); !day.Equal(c.day) {
+		c.day, c.quotaUsed = day, 0
+	}
+
+	if c.DailyQuota > 0 && c.quotaUsed >= c.DailyQuota {
+		c.quotaExceeded++
+		return c.Client, c.day.AddDate(0, 0, 1).Sub(now), "daily quota exceeded"
+	}
+
+	if c.limiter != nil {
+		r := c.limiter.ReserveN(now, 1)
+		if delay := r.DelayFrom(now); delay > 0 {
+			r.CancelAt(now)
+			c.rateLimited++
+			return c.Client, delay, "rate limit exceeded"
+		}
+	}
+
+	c.requests++
+	c.quotaUsed++
+	return c.Client, 0, ""
+}
+
+func (c *client) usage(now time.Time) ClientUsageResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	resp := ClientUsageResponse{
+		Name:          c.Name,
+		Requests:      c.requests,
+		RateLimited:   c.rateLimited,
+		QuotaExceeded: c.quotaExceeded,
+	}
+	if c.day.Equal(utcDay(now)) {
+		resp.QuotaUsed = c.quotaUsed
+	}
+	if c.DailyQuota > 0 {
+		quota, remaining := c.DailyQuota, max(c.DailyQuota-resp.QuotaUsed, 0)
+		resp.DailyQuota, resp.QuotaRemaining = &quota, &remaining
+	}
+	if !c.lastSeen.IsZero() {
+		lastSeen := c.lastSeen.UTC()
+		resp.LastSeen = &lastSeen
+	}
+
+	return resp
+}
+
+func utcDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticator_Middleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      Config
+		key      string
+		wantCode int
+		wantName string
+	}{
+		{
+			name:     "disabled",
+			cfg:      Config{Clients: []Client{{Name: "some-client", Key: "some-key"}}},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "valid key",
+			cfg:      Config{Enabled: true, Clients: []Client{{Name: "some-client", Key: "some-key"}}},
+			key:      "some-key",
+			wantCode: http.StatusOK,
+			wantName: "some-client",
+		},
+		{
+			name:     "missing key",
+			cfg:      Config{Enabled: true, Clients: []Client{{Name: "some-client", Key: "some-key"}}},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "invalid key",
+			cfg:      Config{Enabled: true, Clients: []Client{{Name: "some-client", Key: "some-key"}}},
+			key:      "some-other-key",
+			wantCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAuthenticator(tt.cfg)
+			var gotName string
+			e := newEcho(a, func(ctx echo.Context) error {
+				c, _ := ctx.Get(clientKey).(Client)
+				gotName = c.Name
+				return ctx.NoContent(http.StatusOK)
+			})
+
+			rec := serve(e, "/", tt.key)
+			require.Equal(t, tt.wantCode, rec.Code)
+			require.Equal(t, tt.wantName, gotName)
+		})
+	}
+}
+
+func TestAuthenticator_Middleware_rateLimit(t *testing.T) {
+	a := NewAuthenticator(Config{Enabled: true, Clients: []Client{{Name: "some-client", Key: "some-key", RateLimit: 0.5, Burst: 1}}})
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	a.now = func() time.Time { return now }
+	e := newEcho(a, ok)
+
+	require.Equal(t, http.StatusOK, serve(e, "/", "some-key").Code)
+
+	rec := serve(e, "/", "some-key")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "2", rec.Header().Get("Retry-After"))
+
+	now = now.Add(2 * time.Second)
+	require.Equal(t, http.StatusOK, serve(e, "/", "some-key").Code)
+}
+
+func TestAuthenticator_Middleware_dailyQuota(t *testing.T) {
+	a := NewAuthenticator(Config{Enabled: true, Clients: []Client{{Name: "some-client", Key: "some-key", DailyQuota: 2}}})
+	now := time.Date(2022, 6, 1, 23, 0, 0, 0, time.UTC)
+	a.now = func() time.Time { return now }
+	e := newEcho(a, ok)
+
+	require.Equal(t, http.StatusOK, serve(e, "/", "some-key").Code)
+	require.Equal(t, http.StatusOK, serve(e, "/", "some-key").Code)
+
+	rec := serve(e, "/", "some-key")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "3600", rec.Header().Get("Retry-After"))
+
+	// The quota resets at midnight UTC
+	now = now.Add(time.Hour)
+	require.Equal(t, http.StatusOK, serve(e, "/", "some-key").Code)
+}
+
+func TestAuthenticator_Middleware_cacheControl(t *testing.T) {
+	handler := func(ctx echo.Context) error {
+		ctx.Response().Header().Set(echo.HeaderCacheControl, "public, max-age=60")
+		return ctx.NoContent(http.StatusOK)
+	}
+	cfg := Config{Clients: []Client{{Name: "some-client", Key: "some-key"}}}
+
+	rec := serve(newEcho(NewAuthenticator(cfg), handler), "/", "")
+	require.Equal(t, "public, max-age=60", rec.Header().Get(echo.HeaderCacheControl))
+
+	cfg.Enabled = true
+	rec = serve(newEcho(NewAuthenticator(cfg), handler), "/", "some-key")
+	require.Equal(t, "private, max-age=60", rec.Header().Get(echo.HeaderCacheControl))
+}
+
+func TestAuthenticator_Update(t *testing.T) {
+	a := NewAuthenticator(Config{Enabled: true, Clients: []Client{{Name: "some-client", Key: "some-key", DailyQuota: 1}}})
+	e := newEcho(a, ok)
+
+	require.Equal(t, http.StatusOK, serve(e, "/", "some-key").Code)
+	require.Equal(t, http.StatusTooManyRequests, serve(e, "/", "some-key").Code)
+
+	// Usage is kept when the key is rotated and the quota raised
+	a.Update(Config{Enabled: true, Clients: []Client{{Name: "some-client", Key: "some-rotated-key", DailyQuota: 2}}})
+	require.Equal(t, http.StatusUnauthorized, serve(e, "/", "some-key").Code)
+	require.Equal(t, http.StatusOK, serve(e, "/", "some-rotated-key").Code)
+	require.Equal(t, http.StatusTooManyRequests, serve(e, "/", "some-rotated-key").Code)
+}
+
+func TestAuthenticator_GetUsage(t *testing.T) {
+	a := NewAuthenticator(Config{Enabled: true, Clients: []Client{
+		{Name: "some-client", Key: "some-key", DailyQuota: 1},
+		{Name: "admin", Key: "admin-key", Admin: true},
+	}})
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	a.now = func() time.Time { return now }
+
+	e := echo.New()
+	e.Use(a.Middleware())
+	e.GET("/usage", a.GetUsage, a.RequireAdmin())
+
+	require.Equal(t, http.StatusUnauthorized, serve(e, "/usage", "").Code)
+	require.Equal(t, http.StatusForbidden, serve(e, "/usage", "some-key").Code)
+	require.Equal(t, http.StatusTooManyRequests, serve(e, "/usage", "some-key").Code)
+
+	rec := serve(e, "/usage", "admin-key")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp UsageResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, []ClientUsageResponse{
+		{Name: "admin", Requests: 1, QuotaUsed: 1, LastSeen: &now},
+		{
+			Name:           "some-client",
+			Requests:       1,
+			QuotaExceeded:  1,
+			DailyQuota:     ptr(1),
+			QuotaUsed:      1,
+			QuotaRemaining: ptr(0),
+			LastSeen:       &now,
+		},
+	}, resp.Clients)
+}
+
+func newEcho(a *Authenticator, handler echo.HandlerFunc) *echo.Echo {
+	e := echo.New()
+	e.Use(a.Middleware())
+	e.GET("/", handler)
+	return e
+}
+
+func serve(e *echo.Echo, path string, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if key != "" {
+		req.Header.Set(DefaultHeader, key)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func ok(ctx echo.Context) error {
+	return ctx.NoContent(http.StatusOK)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
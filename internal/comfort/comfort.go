@@ -0,0 +1,96 @@
+// Package comfort computes how the weather feels from temperature, humidity and
+// wind speed. Values are computed from the normalised observation rather than
+// taken from a provider, so they are consistent whichever provider answered.
+// Temperatures are in degrees Celsius, relative humidity in percent and wind
+// speeds in kilometres per hour.
+package comfort
+
+import "math"
+
+const (
+	// Magnus formula coefficients over water for -45°C to 60°C. See
+	// Alduchov and Eskridge (1996).
+	magnusA = 17.625
+	magnusB = 243.04
+
+	// heatIndexThreshold is the temperature below which the heat index is not
+	// defined (80°F).
+	heatIndexThreshold = 26.7
+	// windChillMaxTemp and windChillMinSpeed bound the conditions where the
+	// wind chill index is defined.
+	windChillMaxTemp  = 10
+	windChillMinSpeed = 4.8
+)
+
+// DewPoint returns the temperature to which air must be cooled to become
+// saturated, using the Magnus formula. False is returned when there is no
+// humidity, since dry air has no dew point.
+func DewPoint(temp float64, humidity float64) (float64, bool) {
+	if humidity <= 0 {
+		return 0, false
+	}
+
+	gamma := math.Log(humidity/100) + magnusA*temp/(magnusB+temp)
+	return magnusB * gamma / (magnusA - gamma), true
+}
+
+// ApparentTemperature returns the temperature perceived by a person in the
+// shade, using the Steadman (1994) formula adopted by the Australian Bureau of
+// Meteorology. See http://www.bom.gov.au/info/thermal_stress/
+func ApparentTemperature(temp float64, humidity float64, windSpeed float64) float64 {
+	vapourPressure := humidity / 100 * 6.105 * math.Exp(17.27*temp/(237.7+temp)) // hPa
+	windMetresPerSec := windSpeed / 3.6
+	return temp + 0.33*vapourPressure - 0.70*windMetresPerSec - 4.00
+}
+
+// HeatIndex returns the US National Weather Service heat index. False is
+// returned when the temperature is too low for the heat index to apply. See
+// https://www.wpc.ncep.noaa.gov/html/heatindex_equation.shtml
+func HeatIndex(temp float64, humidity float64) (float64, bool) {
+	if temp < heatIndexThreshold {
+		return 0, false
+	}
+
+	t, rh := celsiusToFahrenheit(temp), humidity
+
+	// The simple formula is used when it results in less than 80°F
+	hi := 0.5 * (t + 61 + (t-68)*1.2 + rh*0.094)
+	if (hi+t)/2 < 80 {
+		return fahrenheitToCelsius(hi), true
+	}
+
+	// Rothfusz regression
+	hi = -42.379 + 2.04901523*t + 10.14333127*rh -
+		0.22475541*t*rh - 0.00683783*t*t -
+		0.05481717*rh*rh + 0.00122874*t*t*rh +
+		0.00085282*t*rh*rh - 0.00000199*t*t*rh*rh
+
+	switch {
+	case rh < 13 && t >= 80 && t <= 112:
+		hi -= (13 - rh) / 4 * math.Sqrt((17-math.Abs(t-95))/17)
+	case rh > 85 && t >= 80 && t <= 87:
+		hi += (rh - 85) / 10 * (87 - t) / 5
+	}
+
+	return fahrenheitToCelsius(hi), true
+}
+
+// WindChill returns the wind chill index used by Environment Canada and the US
+// National Weather Service. False is returned when it is too warm or the wind
+// is too light for wind chill to apply.
+func WindChill(temp float64, windSpeed float64) (float64, bool) {
+	if temp > windChillMaxTemp || windSpeed < windChillMinSpeed {
+		return 0, false
+	}
+
+	v := math.Pow(windSpeed, 0.16)
+	return 13.12 + 0.6215*temp - 11.37*v + 0.3965*temp*v, true
+}
+
+func celsiusToFahrenheit(c float64) float64 {
+	return c*9/5 + 32
+}
+
+func fahrenheitToCelsius(f float64) float64 {
+	return (f - 32) * 5 / 9
+}
@@ -0,0 +1,104 @@
+package comfort
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDewPoint(t *testing.T) {
+	// Reference values from the NWS dew point calculator
+	tests := []struct {
+		temp     float64
+		humidity float64
+		want     float64
+	}{
+		{temp: 20, humidity: 50, want: 9.3},
+		{temp: 30, humidity: 70, want: 23.9},
+		{temp: 0, humidity: 80, want: -3.0},
+		{temp: 25, humidity: 100, want: 25},
+	}
+
+	for _, tt := range tests {
+		got, ok := DewPoint(tt.temp, tt.humidity)
+		require.True(t, ok)
+		require.InDelta(t, tt.want, got, 0.2, "%v°C %v%%", tt.temp, tt.humidity)
+	}
+
+	// Dry air has no dew point
+	_, ok := DewPoint(20, 0)
+	require.False(t, ok)
+}
+
+func TestApparentTemperature(t *testing.T) {
+	// Reference values computed from the Bureau of Meteorology's published formula
+	tests := []struct {
+		temp      float64
+		humidity  float64
+		windSpeed float64
+		want      float64
+	}{
+		{temp: 25, humidity: 50, windSpeed: 7.2, want: 24.8},
+		{temp: 35, humidity: 30, windSpeed: 0, want: 36.6},
+		{temp: 10, humidity: 80, windSpeed: 36, want: 2.3},
+	}
+
+	for _, tt := range tests {
+		require.InDelta(t, tt.want, ApparentTemperature(tt.temp, tt.humidity, tt.windSpeed), 0.1,
+			"%v°C %v%% %vkm/h", tt.temp, tt.humidity, tt.windSpeed)
+	}
+}
+
+func TestHeatIndex(t *testing.T) {
+	// Reference values from the NWS heat index chart, converted from °F
+	tests := []struct {
+		name     string
+		temp     float64
+		humidity float64
+		want     float64
+		wantOK   bool
+	}{
+		{name: "90°F 50%", temp: 32.2, humidity: 50, want: 35, wantOK: true},    // 95°F
+		{name: "100°F 40%", temp: 37.8, humidity: 40, want: 42.8, wantOK: true}, // 109°F
+		{name: "80°F 40%", temp: 26.7, humidity: 40, want: 26.7, wantOK: true},  // 80°F
+		{name: "too cold", temp: 20, humidity: 50, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := HeatIndex(tt.temp, tt.humidity)
+			require.Equal(t, tt.wantOK, ok)
+			if tt.wantOK {
+				require.InDelta(t, tt.want, got, 0.6)
+			}
+		})
+	}
+}
+
+func TestWindChill(t *testing.T) {
+	// Reference values from the Environment Canada wind chill index table
+	tests := []struct {
+		name      string
+		temp      float64
+		windSpeed float64
+		want      float64
+		wantOK    bool
+	}{
+		{name: "0°C 10km/h", temp: 0, windSpeed: 10, want: -3, wantOK: true},
+		{name: "-10°C 20km/h", temp: -10, windSpeed: 20, want: -18, wantOK: true},
+		{name: "-20°C 40km/h", temp: -20, windSpeed: 40, want: -34, wantOK: true},
+		{name: "5°C 30km/h", temp: 5, windSpeed: 30, want: 0, wantOK: true},
+		{name: "too warm", temp: 15, windSpeed: 30, wantOK: false},
+		{name: "too calm", temp: 0, windSpeed: 3, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := WindChill(tt.temp, tt.windSpeed)
+			require.Equal(t, tt.wantOK, ok)
+			if tt.wantOK {
+				require.InDelta(t, tt.want, got, 0.5)
+			}
+		})
+	}
+}
@@ -1,64 +1,251 @@
 package config
 
 import (
+	"context"
 	"errors"
 	"flag"
+	"io"
 	"os"
+	"reflect"
 	"time"
 
-	"github.com/kelseyhightower/envconfig"
 	"gopkg.in/yaml.v2"
+
+	"github.com/joshjon/sydneyweather/internal/weather"
+)
+
+// maskedSecret replaces secrets when the config is printed.
+const maskedSecret = "REDACTED"
+
+// Defaults used when values are not configured.
+const (
+	defaultServerPort          = 8080
+	defaultCacheExpiry         = 3 * time.Second
+	defaultForecastCacheExpiry = 30 * time.Minute
+	defaultLogLevel            = "info"
+	defaultSampleRatio         = 1
+	// defaultShutdownTimeout is how long in-flight requests are given to
+	// complete on shutdown. The rest of shutdown takes up to 3s more, which
+	// fits within the 10s docker stop grace period.
+	defaultShutdownTimeout = 6 * time.Second
+	// defaultSecretRefresh is how often secrets are resolved again to pick up
+	// rotated keys.
+	defaultSecretRefresh = 5 * time.Minute
 )
 
+// Config is the service config. Fields tagged 'reload:"live"' are applied
+// while the service runs when the config is reloaded. Changes to other fields
+// require a restart. Fields tagged 'secret:"true"' can hold a reference to a
+// secret, e.g. 'file:///run/secrets/key' or 'env:NAME', which is resolved on
+// load and again every secret refresh interval.
 type Config struct {
-	ServerPort         int           `yaml:"serverPort" validate:"required"`
-	CacheExpiry        time.Duration `yaml:"cacheExpiry" validate:"required"`
-	WeatherStackAPIKey string        `yaml:"weatherStackAPIKey" envconfig:"WEATHER_STACK_KEY" validate:"required"`
-	OpenWeatherAPIKey  string        `yaml:"openWeatherAPIKey" envconfig:"OPEN_WEATHER_KEY" validate:"required"`
+	ServerPort            int           `yaml:"serverPort" validate:"required"`
+	ShutdownTimeout       time.Duration `yaml:"shutdownTimeout"`
+	CacheExpiry           time.Duration `yaml:"cacheExpiry" validate:"required" reload:"live"`
+	ForecastCacheExpiry   time.Duration `yaml:"forecastCacheExpiry" validate:"required" reload:"live"`
+	Providers             []string      `yaml:"providers" reload:"live"`
+	ProviderTimeout       time.Duration `yaml:"providerTimeout" reload:"live"`
+	WeatherStackAPIKey    string        `yaml:"weatherStackAPIKey" env:"WEATHER_STACK_KEY" validate:"required" reload:"live" secret:"true"`
+	OpenWeatherAPIKey     string        `yaml:"openWeatherAPIKey" env:"OPEN_WEATHER_KEY" validate:"required" reload:"live" secret:"true"`
+	SecretRefreshInterval time.Duration `yaml:"secretRefreshInterval"`
+	LogLevel              string        `yaml:"logLevel" reload:"live"`
+	Validation            Validation    `yaml:"validation"`
+	Outlier               Outlier       `yaml:"outlier"`
+	Tracing               Tracing       `yaml:"tracing"`
+	Health                Health        `yaml:"health"`
+	Auth                  Auth          `yaml:"auth" reload:"live"`
+
+	src source // Where the config was loaded from
+}
+
+// source is where a config is loaded from. Values are layered in increasing
+// precedence: built-in defaults, the optional yaml file, env vars, then flags.
+type source struct {
+	path  string
+	flags map[string]string // Raw values of the flags that were set
+}
+
+// Validation configures the plausible ranges that provider observations must
+// fall within. Locations are keyed by city name and override the default limits
+// per bound.
+type Validation struct {
+	Limits    weather.Limits            `yaml:"limits"`
+	Locations map[string]weather.Limits `yaml:"locations"`
+}
+
+// Outlier configures how often every provider is sampled to compare readings,
+// how many samples are kept and how far (°C and km/h) a provider's mean
+// deviation from the consensus can drift before it is demoted.
+type Outlier struct {
+	Interval             time.Duration `yaml:"interval"`
+	Window               int           `yaml:"window"`
+	TemperatureThreshold float64       `yaml:"temperatureThreshold"`
+	WindSpeedThreshold   float64       `yaml:"windSpeedThreshold"`
+}
+
+// Tracing configures where trace spans are exported: 'none', 'stdout' for local
+// debugging, or 'otlp' to send spans to the collector at the endpoint. New
+// traces are sampled at the sample ratio, which defaults to 1 (every trace)
+// when unset and samples none at 0, unless the caller has already decided.
+type Tracing struct {
+	Exporter    string  `yaml:"exporter"`
+	Endpoint    string  `yaml:"endpoint"`
+	Insecure    bool    `yaml:"insecure"`
+	SampleRatio float64 `yaml:"sampleRatio"`
 }
 
-// Load loads config from a yaml file which is specified by the 'config' flag
-// and also from environment variables.
+// Health configures how many consecutive failures open a provider's circuit and
+// how long it stays open. With readyWhileStale, readiness only fails when every
+// provider is down and the cached current conditions are older than maxStaleAge
+// (0 is no limit) or missing.
+type Health struct {
+	FailureThreshold int           `yaml:"failureThreshold"`
+	Cooldown         time.Duration `yaml:"cooldown"`
+	ReadyWhileStale  bool          `yaml:"readyWhileStale"`
+	MaxStaleAge      time.Duration `yaml:"maxStaleAge"`
+}
+
+// Auth configures API key authentication of clients. When enabled, API
+// requests need the key of a client in the header, which defaults to
+// X-API-Key. Clients are listed here and in the optional clients file, a yaml
+// list of clients such as a mounted Kubernetes secret.
+type Auth struct {
+	Enabled     bool     `yaml:"enabled"`
+	Header      string   `yaml:"header"`
+	ClientsFile string   `yaml:"clientsFile"`
+	Clients     []Client `yaml:"clients"`
+}
+
+// Client is a consumer of the service. Requests are limited to the rate limit
+// per second, allowing bursts of up to burst requests (defaults to the rate
+// rounded up), and to the daily quota per UTC day. Zero is no limit. Admin
+// clients can read the usage of every client.
+type Client struct {
+	Name       string  `yaml:"name"`
+	Key        string  `yaml:"key" secret:"true"`
+	RateLimit  float64 `yaml:"rateLimit"`
+	Burst      int     `yaml:"burst"`
+	DailyQuota int     `yaml:"dailyQuota"`
+	Admin      bool    `yaml:"admin"`
+}
+
+// Load loads config from the yaml file specified by the optional 'config' flag
+// and overrides it with env vars and then flags, which exist for every setting
+// except maps and lists of structs, i.e. validation.locations and auth.clients.
+// Settings that are not set anywhere have built-in defaults. Every problem with
+// the loaded config is reported in a single *ValidationError. Use a Watcher to
+// reload the config when the file changes.
 func Load() (*Config, error) {
-	var cfg Config
-	var filepath string
+	return load(flag.CommandLine, os.Args[1:])
+}
 
-	flag.StringVar(&filepath, "config", "", "yaml config file path")
-	flag.Parse()
+func load(fs *flag.FlagSet, args []string) (*Config, error) {
+	var src source
+	fs.StringVar(&src.path, "config", "", "yaml config file path (optional)")
+	src.flags = registerFlags(fs)
 
-	if filepath == "" {
-		return nil, errors.New("required flag 'config' was not provided")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
 	}
 
-	if err := readFile(filepath, &cfg); err != nil {
-		return nil, err
+	return read(src)
+}
+
+// defaultConfig returns the built-in defaults, the lowest layer of config.
+func defaultConfig() Config {
+	return Config{
+		ServerPort:            defaultServerPort,
+		ShutdownTimeout:       defaultShutdownTimeout,
+		CacheExpiry:           defaultCacheExpiry,
+		ForecastCacheExpiry:   defaultForecastCacheExpiry,
+		SecretRefreshInterval: defaultSecretRefresh,
+		LogLevel:              defaultLogLevel,
+		Tracing:               Tracing{SampleRatio: defaultSampleRatio},
 	}
+}
 
-	if err := readEnv(&cfg); err != nil {
-		return nil, err
+// read reads each layer of config from the source, resolves secrets and
+// validates it, then applies defaults to unset durations.
+func read(src source) (*Config, error) {
+	cfg := defaultConfig()
+	cfg.src = src
+
+	if src.path != "" {
+		if err := readFile(src.path, &cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	problems := cfg.override(envOverride)
+	problems = append(problems, cfg.override(flagOverride(src.flags))...)
+
+	if cfg.Auth.ClientsFile != "" {
+		if err := readClientsFile(cfg.Auth.ClientsFile, &cfg.Auth); err != nil {
+			problems = append(problems, "auth.clientsFile could not be read: "+err.Error())
+		}
+	}
+
+	problems = append(problems, cfg.resolveSecrets(context.Background())...)
+
+	var invalid *ValidationError
+	if err := cfg.Validate(); errors.As(err, &invalid) {
+		problems = append(problems, invalid.Problems...)
+	}
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems, Config: &cfg}
+	}
+
+	if cfg.ShutdownTimeout <= 0 {
+		cfg.ShutdownTimeout = defaultShutdownTimeout
+	}
+	if cfg.SecretRefreshInterval <= 0 {
+		cfg.SecretRefreshInterval = defaultSecretRefresh
 	}
 
 	return &cfg, nil
 }
 
-func readFile(filePath string, out *Config) error {
-	f, err := os.Open(filePath)
+// Print writes the config as yaml with secrets masked.
+func (c *Config) Print(w io.Writer) error {
+	masked := *c
+	// Copy lists holding secrets so that the config itself is not masked
+	masked.Auth.Clients = append([]Client(nil), c.Auth.Clients...)
+
+	secretFields(reflect.ValueOf(&masked).Elem(), "", func(_ string, v reflect.Value) {
+		if !v.IsZero() {
+			v.SetString(maskedSecret)
+		}
+	})
+
+	return yaml.NewEncoder(w).Encode(&masked)
+}
+
+// readClientsFile appends the clients listed in the file to those configured.
+func readClientsFile(filePath string, auth *Auth) error {
+	b, err := os.ReadFile(filePath)
 	if err != nil {
 		return err
 	}
 
-	if err = yaml.NewDecoder(f).Decode(out); err != nil {
+	var clients []Client
+	if err = yaml.UnmarshalStrict(b, &clients); err != nil {
 		return err
 	}
 
-	return f.Close()
+	auth.Clients = append(auth.Clients, clients...)
+	return nil
 }
 
-func readEnv(out *Config) error {
-	err := envconfig.Process("", out)
+func readFile(filePath string, out *Config) error {
+	f, err := os.Open(filePath)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	// An empty file has no document rather than an empty one
+	if err = yaml.NewDecoder(f).Decode(out); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	return f.Close()
 }
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestLoad_layers(t *testing.T) {
+	path := writeConfig(t, testConfig+"cacheExpiry: 10s\nforecastCacheExpiry: 1h\nproviderTimeout: 1s\n")
+	t.Setenv("FORECAST_CACHE_EXPIRY", "2h")
+	t.Setenv("PROVIDER_TIMEOUT", "2s")
+	t.Setenv("OUTLIER_WINDOW", "12")
+	t.Setenv("SERVER_PORT", "")
+
+	cfg, err := load(newFlagSet(), []string{
+		"-config", path,
+		"-providerTimeout", "3s",
+		"-providers", "openweather, weatherstack",
+		"-health.readyWhileStale",
+		"-validation.limits.temperature.max", "45",
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, 8080, cfg.ServerPort)                        // File, with the empty env var ignored
+	require.Equal(t, defaultShutdownTimeout, cfg.ShutdownTimeout) // Default
+	require.Equal(t, 1.0, cfg.Tracing.SampleRatio)                // Default
+	require.Equal(t, 10*time.Second, cfg.CacheExpiry)             // File
+	require.Equal(t, 2*time.Hour, cfg.ForecastCacheExpiry)        // Env over file
+	require.Equal(t, 3*time.Second, cfg.ProviderTimeout)          // Flag over env and file
+	require.Equal(t, []string{"openweather", "weatherstack"}, cfg.Providers)
+	require.Equal(t, 12, cfg.Outlier.Window)
+	require.True(t, cfg.Health.ReadyWhileStale)
+	require.Equal(t, 45.0, *cfg.Validation.Limits.Temperature.Max)
+	require.Nil(t, cfg.Validation.Limits.Temperature.Min)
+}
+
+func TestLoad_withoutFile(t *testing.T) {
+	t.Setenv("WEATHER_STACK_KEY", "some-key")
+	t.Setenv("OPEN_WEATHER_KEY", "some-key")
+
+	cfg, err := load(newFlagSet(), []string{"-serverPort", "9090"})
+	require.NoError(t, err)
+	require.Equal(t, 9090, cfg.ServerPort)
+	require.Equal(t, defaultCacheExpiry, cfg.CacheExpiry)
+	require.Equal(t, defaultLogLevel, cfg.LogLevel)
+}
+
+func TestLoad_emptyFile(t *testing.T) {
+	path := writeConfig(t, "")
+	t.Setenv("WEATHER_STACK_KEY", "some-key")
+	t.Setenv("OPEN_WEATHER_KEY", "some-key")
+
+	cfg, err := load(newFlagSet(), []string{"-config", path})
+	require.NoError(t, err)
+	require.Equal(t, defaultServerPort, cfg.ServerPort)
+}
+
+func TestLoad_invalidOverrides(t *testing.T) {
+	path := writeConfig(t, testConfig)
+	t.Setenv("CACHE_EXPIRY", "soon")
+
+	_, err := load(newFlagSet(), []string{"-config", path, "-serverPort", "http", "-tracing.insecure=maybe"})
+
+	var invalid *ValidationError
+	require.ErrorAs(t, err, &invalid)
+	require.Equal(t, []string{
+		"cacheExpiry (CACHE_EXPIRY) must be a duration e.g. 30s, got 'soon'",
+		"serverPort (-serverPort) must be an integer, got 'http'",
+		"tracing.insecure (-tracing.insecure) must be true or false, got 'maybe'",
+	}, invalid.Problems)
+	require.Equal(t, 8080, invalid.Config.ServerPort)
+	require.Equal(t, 3*time.Second, invalid.Config.CacheExpiry)
+}
+
+func TestLoad_clientsFile(t *testing.T) {
+	clientsFile := writeConfig(t, "- { name: file-client, key: file-key, dailyQuota: 100 }\n")
+	path := writeConfig(t, testConfig+"auth: { enabled: true, clientsFile: "+clientsFile+", clients: [{ name: some-client, key: some-client-key }] }\n")
+
+	cfg, err := load(newFlagSet(), []string{"-config", path})
+	require.NoError(t, err)
+	require.Equal(t, []Client{
+		{Name: "some-client", Key: "some-client-key"},
+		{Name: "file-client", Key: "file-key", DailyQuota: 100},
+	}, cfg.Auth.Clients)
+
+	_, err = load(newFlagSet(), []string{"-config", path, "-auth.clientsFile", clientsFile + ".missing"})
+	var invalid *ValidationError
+	require.ErrorAs(t, err, &invalid)
+	require.Equal(t, []string{"auth.clientsFile could not be read: open " + clientsFile + ".missing: no such file or directory"}, invalid.Problems)
+}
+
+func TestConfig_Print(t *testing.T) {
+	cfg := validConfig()
+	cfg.OpenWeatherAPIKey = ""
+	cfg.Auth.Clients = []Client{{Name: "some-client", Key: "some-client-key"}}
+
+	var buf bytes.Buffer
+	require.NoError(t, cfg.Print(&buf))
+
+	var printed map[string]any
+	require.NoError(t, yaml.Unmarshal(buf.Bytes(), &printed))
+	require.Equal(t, maskedSecret, printed["weatherStackAPIKey"])
+	require.Equal(t, "", printed["openWeatherAPIKey"])
+	require.Equal(t, "3s", printed["cacheExpiry"])
+	require.NotContains(t, buf.String(), "some-key")
+	require.NotContains(t, buf.String(), "some-client-key")
+
+	// The config itself is not masked
+	require.Equal(t, "some-key", cfg.WeatherStackAPIKey)
+	require.Equal(t, "some-client-key", cfg.Auth.Clients[0].Key)
+}
+
+func TestEnvName(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "serverPort", want: "SERVER_PORT"},
+		{path: "outlier.windSpeedThreshold", want: "OUTLIER_WIND_SPEED_THRESHOLD"},
+		{path: "validation.limits.cloudCover.min", want: "VALIDATION_LIMITS_CLOUD_COVER_MIN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			require.Equal(t, tt.want, envName(tt.path))
+		})
+	}
+}
+
+func newFlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
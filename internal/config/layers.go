@@ -0,0 +1,197 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// setting is a config value that can be overridden by an env var or a flag.
+// Its path is the yaml keys leading to it joined by '.', which is also the name
+// of its flag.
+type setting struct {
+	path  string
+	env   string
+	value reflect.Value
+}
+
+// settings returns every setting of the struct s. Maps and lists of structs are
+// skipped since their keys and items cannot be expressed in env var and flag
+// names, so they can only be set in the yaml file.
+func settings(s reflect.Value, prefix string) []setting {
+	var all []setting
+
+	for i := 0; i < s.NumField(); i++ {
+		field := s.Type().Field(i)
+		key := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if !field.IsExported() || key == "" || key == "-" {
+			continue
+		}
+
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		value := s.Field(i)
+		switch {
+		case value.Kind() == reflect.Map:
+			continue
+		case value.Kind() == reflect.Slice && value.Type().Elem().Kind() != reflect.String:
+			continue
+		case value.Kind() == reflect.Struct:
+			all = append(all, settings(value, path)...)
+			continue
+		}
+
+		env := field.Tag.Get("env")
+		if env == "" {
+			env = envName(path)
+		}
+		all = append(all, setting{path: path, env: env, value: value})
+	}
+
+	return all
+}
+
+// envName returns the env var of a setting path, e.g. OUTLIER_WIND_SPEED_THRESHOLD
+// for outlier.windSpeedThreshold.
+func envName(path string) string {
+	var b strings.Builder
+	for i, r := range path {
+		switch {
+		case r == '.':
+			b.WriteRune('_')
+		case unicode.IsUpper(r) && i > 0 && unicode.IsLower(rune(path[i-1])):
+			b.WriteRune('_')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(unicode.ToUpper(r))
+		}
+	}
+	return b.String()
+}
+
+// override sets each setting that lookup has a value for. Lookup also returns
+// where the value came from. A problem is returned for each value that cannot
+// be parsed.
+func (c *Config) override(lookup func(s setting) (raw string, source string, ok bool)) []string {
+	var problems []string
+	for _, s := range settings(reflect.ValueOf(c).Elem(), "") {
+		raw, source, ok := lookup(s)
+		if !ok {
+			continue
+		}
+		if err := set(s.value, raw); err != nil {
+			problems = append(problems, fmt.Sprintf("%s (%s) %s", s.path, source, err))
+		}
+	}
+	return problems
+}
+
+// envOverride looks up the env var of a setting. Empty env vars are ignored
+// since they are usually passed through unset, e.g. by 'docker run -e'.
+func envOverride(s setting) (string, string, bool) {
+	raw := os.Getenv(s.env)
+	return raw, s.env, raw != ""
+}
+
+// flagOverride looks up the flag of a setting in the flags that were set.
+func flagOverride(flags map[string]string) func(s setting) (string, string, bool) {
+	return func(s setting) (string, string, bool) {
+		raw, ok := flags[s.path]
+		return raw, "-" + s.path, ok
+	}
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// set parses raw into v. Lists are comma separated.
+func set(v reflect.Value, raw string) error {
+	if v.Kind() == reflect.Pointer {
+		elem := reflect.New(v.Type().Elem())
+		if err := set(elem.Elem(), raw); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("must be a duration e.g. 30s, got '%s'", raw)
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(raw)
+	case v.Kind() == reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("must be an integer, got '%s'", raw)
+		}
+		v.SetInt(int64(n))
+	case v.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("must be a number, got '%s'", raw)
+		}
+		v.SetFloat(f)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("must be true or false, got '%s'", raw)
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("has unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+// flagValue records the raw value of a setting's flag, which is parsed once
+// the lower layers have been read.
+type flagValue struct {
+	path   string
+	flags  map[string]string
+	isBool bool
+}
+
+func (f *flagValue) String() string {
+	return ""
+}
+
+func (f *flagValue) Set(raw string) error {
+	f.flags[f.path] = raw
+	return nil
+}
+
+func (f *flagValue) IsBoolFlag() bool {
+	return f.isBool
+}
+
+// registerFlags defines a flag for every setting on fs. The raw values of the
+// flags that are set are recorded in the returned map.
+func registerFlags(fs *flag.FlagSet) map[string]string {
+	flags := make(map[string]string)
+	for _, s := range settings(reflect.ValueOf(&Config{}).Elem(), "") {
+		value := &flagValue{path: s.path, flags: flags, isBool: s.value.Kind() == reflect.Bool}
+		fs.Var(value, s.path, "overrides "+s.path+" and the "+s.env+" env var")
+	}
+	return flags
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// secretTimeout limits how long resolving every secret of a config can take.
+const secretTimeout = 10 * time.Second
+
+// SecretProvider resolves references to secrets held outside the config. The
+// reference includes its scheme, e.g. 'file:///run/secrets/key'.
+type SecretProvider interface {
+	Resolve(ctx context.Context, ref string) (string, error)
+}
+
+var (
+	secretProvidersMu sync.RWMutex
+	secretProviders   = map[string]SecretProvider{
+		"file": FileSecretProvider{},
+		"env":  EnvSecretProvider{},
+	}
+)
+
+// RegisterSecretProvider makes a provider resolve references with the scheme,
+// replacing any provider already registered for it.
+func RegisterSecretProvider(scheme string, p SecretProvider) {
+	secretProvidersMu.Lock()
+	defer secretProvidersMu.Unlock()
+	secretProviders[scheme] = p
+}
+
+// secretProvider returns the provider of a reference, which is false when the
+// value is not a reference to a registered scheme.
+func secretProvider(value string) (SecretProvider, bool) {
+	scheme, _, ok := strings.Cut(value, ":")
+	if !ok {
+		return nil, false
+	}
+
+	secretProvidersMu.RLock()
+	defer secretProvidersMu.RUnlock()
+	p, ok := secretProviders[scheme]
+	return p, ok
+}
+
+// FileSecretProvider reads secrets from local files such as Docker and
+// Kubernetes secrets mounted in the container, e.g. 'file:///run/secrets/key'.
+// Surrounding whitespace such as a trailing newline is removed.
+type FileSecretProvider struct{}
+
+func (FileSecretProvider) Resolve(_ context.Context, ref string) (string, error) {
+	path, ok := strings.CutPrefix(ref, "file://")
+	if !ok || path == "" {
+		return "", fmt.Errorf("must be in file:///path format")
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(b)), nil
+}
+
+// EnvSecretProvider reads secrets from environment variables, e.g. 'env:NAME'.
+type EnvSecretProvider struct{}
+
+func (EnvSecretProvider) Resolve(_ context.Context, ref string) (string, error) {
+	name, _ := strings.CutPrefix(ref, "env:")
+	if name == "" {
+		return "", fmt.Errorf("must be in env:NAME format")
+	}
+
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+
+	return value, nil
+}
+
+// resolveSecrets replaces the references held by fields tagged 'secret:"true"'
+// with the secrets they refer to. Other values are used as is. A problem is
+// returned for each reference that cannot be resolved, which never includes a
+// secret.
+func (c *Config) resolveSecrets(ctx context.Context) []string {
+	ctx, cancel := context.WithTimeout(ctx, secretTimeout)
+	defer cancel()
+
+	var problems []string
+	secretFields(reflect.ValueOf(c).Elem(), "", func(path string, v reflect.Value) {
+		ref := v.String()
+		p, ok := secretProvider(ref)
+		if !ok {
+			return
+		}
+
+		secret, err := p.Resolve(ctx, ref)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s secret %s could not be resolved: %s", path, ref, err))
+			return
+		}
+		v.SetString(secret)
+	})
+
+	return problems
+}
+
+// secretFields calls fn with the yaml path and value of each field of the
+// struct s tagged 'secret:"true"', including fields of nested structs and lists
+// of structs.
+func secretFields(s reflect.Value, prefix string, fn func(path string, v reflect.Value)) {
+	for i := 0; i < s.NumField(); i++ {
+		field := s.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		path := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+
+		v := s.Field(i)
+		switch {
+		case field.Tag.Get("secret") == "true":
+			fn(path, v)
+		case v.Kind() == reflect.Struct:
+			secretFields(v, path, fn)
+		case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Struct:
+			for j := 0; j < v.Len(); j++ {
+				secretFields(v.Index(j), fmt.Sprintf("%s[%d]", path, j), fn)
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_resolveSecrets(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "ws_key")
+	require.NoError(t, os.WriteFile(secretFile, []byte("file-key\n"), 0o600))
+	missingFile := filepath.Join(dir, "missing")
+
+	t.Setenv("SOME_SECRET", "env-key")
+	RegisterSecretProvider("stub", stubSecretProvider{"stub:ow_key": "stub-key"})
+
+	tests := []struct {
+		name         string
+		ref          string
+		want         string
+		wantProblems []string
+	}{
+		{name: "literal", ref: "some-key", want: "some-key"},
+		{name: "unregistered scheme", ref: "vault:some-key", want: "vault:some-key"},
+		{name: "file", ref: "file://" + secretFile, want: "file-key"},
+		{name: "env", ref: "env:SOME_SECRET", want: "env-key"},
+		{name: "registered provider", ref: "stub:ow_key", want: "stub-key"},
+		{
+			name: "missing file",
+			ref:  "file://" + missingFile,
+			wantProblems: []string{
+				"weatherStackAPIKey secret file://" + missingFile + " could not be resolved: open " + missingFile + ": no such file or directory",
+			},
+		},
+		{
+			name:         "malformed file",
+			ref:          "file:ws_key",
+			wantProblems: []string{"weatherStackAPIKey secret file:ws_key could not be resolved: must be in file:///path format"},
+		},
+		{
+			name:         "unset env",
+			ref:          "env:SOME_UNSET_SECRET",
+			wantProblems: []string{"weatherStackAPIKey secret env:SOME_UNSET_SECRET could not be resolved: environment variable SOME_UNSET_SECRET is not set"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{WeatherStackAPIKey: tt.ref}
+			require.Equal(t, tt.wantProblems, cfg.resolveSecrets(context.Background()))
+			if tt.wantProblems == nil {
+				require.Equal(t, tt.want, cfg.WeatherStackAPIKey)
+			}
+		})
+	}
+}
+
+func TestWatcher_Reload_rotatedSecret(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "ws_key")
+	require.NoError(t, os.WriteFile(secretFile, []byte("some-key"), 0o600))
+
+	path := writeConfig(t, testConfig+"weatherStackAPIKey: file://"+secretFile+"\n")
+	cfg, err := read(source{path: path})
+	require.NoError(t, err)
+	require.Equal(t, "some-key", cfg.WeatherStackAPIKey)
+
+	var applied *Config
+	w := NewWatcher(cfg, discardLogger(), func(cfg *Config) { applied = cfg })
+
+	// Refreshes that change nothing are not recorded
+	require.Equal(t, ReloadUnchanged, w.Reload(TriggerRefresh).Status)
+	require.Empty(t, w.Results())
+
+	require.NoError(t, os.WriteFile(secretFile, []byte("some-rotated-key"), 0o600))
+	res := w.Reload(TriggerRefresh)
+	require.Equal(t, ReloadApplied, res.Status)
+	require.Equal(t, []string{"weatherStackAPIKey"}, res.Applied)
+	require.Equal(t, "some-rotated-key", applied.WeatherStackAPIKey)
+}
+
+type stubSecretProvider map[string]string
+
+func (p stubSecretProvider) Resolve(_ context.Context, ref string) (string, error) {
+	secret, ok := p[ref]
+	if !ok {
+		return "", errors.New("some-error")
+	}
+	return secret, nil
+}
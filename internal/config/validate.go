@@ -0,0 +1,179 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joshjon/sydneyweather/internal/logging"
+	"github.com/joshjon/sydneyweather/internal/tracing"
+	"github.com/joshjon/sydneyweather/internal/weather"
+)
+
+// providerNames are the providers that can be ordered in the chain.
+var providerNames = []string{"weatherstack", "openweather"}
+
+// ValidationError lists every problem found in a config so that they can all
+// be fixed at once.
+type ValidationError struct {
+	Problems []string
+	Config   *Config // The invalid config, e.g. for printing alongside the problems
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid config: %s", strings.Join(e.Problems, "; "))
+}
+
+// Validate checks that fields tagged 'validate:"required"' are set and that
+// values are within their valid ranges. A *ValidationError listing every
+// problem is returned if the config is invalid.
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	v.required(reflect.ValueOf(c).Elem())
+
+	v.check(c.ServerPort == 0 || validPort(c.ServerPort), "serverPort must be between 1 and 65535")
+	v.nonNegative("shutdownTimeout", c.ShutdownTimeout)
+	v.check(c.CacheExpiry >= 0, "cacheExpiry must be positive")
+	v.check(c.ForecastCacheExpiry >= 0, "forecastCacheExpiry must be positive")
+	v.providers(c.Providers)
+	v.nonNegative("providerTimeout", c.ProviderTimeout)
+	v.apiKey("weatherStackAPIKey", c.WeatherStackAPIKey)
+	v.apiKey("openWeatherAPIKey", c.OpenWeatherAPIKey)
+	v.nonNegative("secretRefreshInterval", c.SecretRefreshInterval)
+
+	if _, err := logging.ParseLevel(c.LogLevel); err != nil {
+		v.add("logLevel must be one of debug, info, warn or error")
+	}
+
+	for _, problem := range weather.NewValidator(c.Validation.Limits, c.Validation.Locations).Check() {
+		v.add("validation." + problem)
+	}
+
+	v.nonNegative("outlier.interval", c.Outlier.Interval)
+	v.check(c.Outlier.Window >= 0, "outlier.window must not be negative")
+	v.check(c.Outlier.TemperatureThreshold >= 0, "outlier.temperatureThreshold must not be negative")
+	v.check(c.Outlier.WindSpeedThreshold >= 0, "outlier.windSpeedThreshold must not be negative")
+
+	switch c.Tracing.Exporter {
+	case "", tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP:
+	default:
+		v.add(fmt.Sprintf("tracing.exporter must be one of %s, %s or %s",
+			tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP))
+	}
+	if c.Tracing.Endpoint != "" {
+		v.check(validHostPort(c.Tracing.Endpoint), "tracing.endpoint must be in host:port format")
+	}
+	v.check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sampleRatio must be between 0 and 1")
+
+	v.check(c.Health.FailureThreshold >= 0, "health.failureThreshold must not be negative")
+	v.nonNegative("health.cooldown", c.Health.Cooldown)
+	v.nonNegative("health.maxStaleAge", c.Health.MaxStaleAge)
+
+	v.auth(c.Auth)
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems, Config: c}
+	}
+	return nil
+}
+
+type validator struct {
+	problems []string
+}
+
+func (v *validator) add(problem string) {
+	v.problems = append(v.problems, problem)
+}
+
+func (v *validator) check(ok bool, problem string) {
+	if !ok {
+		v.add(problem)
+	}
+}
+
+func (v *validator) nonNegative(name string, d time.Duration) {
+	v.check(d >= 0, name+" must not be negative")
+}
+
+// apiKey checks that a set API key has no whitespace, which is usually left
+// over from copying it. Unset keys are reported by the required check.
+func (v *validator) apiKey(name string, key string) {
+	v.check(!strings.ContainsAny(key, " \t\r\n"), name+" must not contain whitespace")
+}
+
+// providers checks that the provider order only names known providers once.
+func (v *validator) providers(names []string) {
+	seen := make(map[string]bool)
+	for _, name := range names {
+		switch {
+		case !slices.Contains(providerNames, name):
+			v.add(fmt.Sprintf("providers has unknown provider '%s', must be one of %s",
+				name, strings.Join(providerNames, ", ")))
+		case seen[name]:
+			v.add(fmt.Sprintf("providers lists '%s' more than once", name))
+		}
+		seen[name] = true
+	}
+}
+
+// auth checks that clients are named, have distinct keys and have limits that
+// are not negative. At least one client is needed when auth is enabled.
+func (v *validator) auth(a Auth) {
+	v.check(!a.Enabled || len(a.Clients) > 0, "auth.clients must list a client when auth is enabled")
+	v.check(!strings.ContainsAny(a.Header, " \t\r\n:"), "auth.header must be a header name")
+
+	names, keys := make(map[string]bool), make(map[string]bool)
+	for i, c := range a.Clients {
+		name := fmt.Sprintf("auth.clients[%d]", i)
+		if c.Name != "" {
+			name = fmt.Sprintf("auth.clients '%s'", c.Name)
+		}
+
+		v.check(c.Name != "", name+" must have a name")
+		v.check(c.Key != "", name+" must have a key")
+		v.check(c.Name == "" || !names[c.Name], name+" is listed more than once")
+		v.check(c.Key == "" || !keys[c.Key], name+" has the same key as another client")
+		v.apiKey(name+" key", c.Key)
+		v.check(c.RateLimit >= 0, name+" rateLimit must not be negative")
+		v.check(c.Burst >= 0, name+" burst must not be negative")
+		v.check(c.DailyQuota >= 0, name+" dailyQuota must not be negative")
+		names[c.Name], keys[c.Key] = true, true
+	}
+}
+
+// required reports each field of the struct tagged 'validate:"required"' that
+// has its zero value. Fields are named by their yaml key along with their env
+// var.
+func (v *validator) required(s reflect.Value) {
+	for i := 0; i < s.NumField(); i++ {
+		field := s.Type().Field(i)
+		if field.Tag.Get("validate") != "required" || !s.Field(i).IsZero() {
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		env := field.Tag.Get("env")
+		if env == "" {
+			env = envName(name)
+		}
+		v.add(name + " (" + env + ") is required")
+	}
+}
+
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
+}
+
+func validHostPort(addr string) bool {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host == "" {
+		return false
+	}
+	n, err := strconv.Atoi(port)
+	return err == nil && validPort(n)
+}
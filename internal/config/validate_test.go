@@ -0,0 +1,126 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshjon/sydneyweather/internal/weather"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name         string
+		modify       func(cfg *Config)
+		wantProblems []string
+	}{
+		{
+			name:   "valid",
+			modify: func(cfg *Config) {},
+		},
+		{
+			name:         "auth without clients",
+			modify:       func(cfg *Config) { cfg.Auth.Enabled = true },
+			wantProblems: []string{"auth.clients must list a client when auth is enabled"},
+		},
+		{
+			name: "required",
+			modify: func(cfg *Config) {
+				cfg.ServerPort, cfg.CacheExpiry = 0, 0
+				cfg.WeatherStackAPIKey, cfg.OpenWeatherAPIKey = "", ""
+			},
+			wantProblems: []string{
+				"serverPort (SERVER_PORT) is required",
+				"cacheExpiry (CACHE_EXPIRY) is required",
+				"weatherStackAPIKey (WEATHER_STACK_KEY) is required",
+				"openWeatherAPIKey (OPEN_WEATHER_KEY) is required",
+			},
+		},
+		{
+			name: "ranges",
+			modify: func(cfg *Config) {
+				cfg.ServerPort = 70000
+				cfg.ShutdownTimeout = -time.Second
+				cfg.CacheExpiry = -time.Second
+				cfg.Providers = []string{"openweather", "darksky", "openweather"}
+				cfg.ProviderTimeout = -time.Second
+				cfg.OpenWeatherAPIKey = "some-key\n"
+				cfg.SecretRefreshInterval = -time.Second
+				cfg.LogLevel = "verbose"
+				cfg.Validation.Locations = map[string]weather.Limits{"sydney": {WindSpeed: weather.Range{Min: ptr(500.0)}}}
+				cfg.Outlier.Window = -1
+				cfg.Tracing = Tracing{Exporter: "jaeger", Endpoint: "localhost", SampleRatio: 2}
+				cfg.Health.Cooldown = -time.Second
+				cfg.Auth = Auth{Enabled: true, Header: "X-API-Key:", Clients: []Client{
+					{Name: "some-client", Key: "some-key", RateLimit: -1},
+					{Name: "some-client", Key: "some-key", DailyQuota: -1},
+					{Burst: -1},
+				}}
+			},
+			wantProblems: []string{
+				"serverPort must be between 1 and 65535",
+				"shutdownTimeout must not be negative",
+				"cacheExpiry must be positive",
+				"providers has unknown provider 'darksky', must be one of weatherstack, openweather",
+				"providers lists 'openweather' more than once",
+				"providerTimeout must not be negative",
+				"openWeatherAPIKey must not contain whitespace",
+				"secretRefreshInterval must not be negative",
+				"logLevel must be one of debug, info, warn or error",
+				"validation.locations.sydney.windSpeed [500, 410] has min above max",
+				"outlier.window must not be negative",
+				"tracing.exporter must be one of none, stdout or otlp",
+				"tracing.endpoint must be in host:port format",
+				"tracing.sampleRatio must be between 0 and 1",
+				"health.cooldown must not be negative",
+				"auth.header must be a header name",
+				"auth.clients 'some-client' rateLimit must not be negative",
+				"auth.clients 'some-client' is listed more than once",
+				"auth.clients 'some-client' has the same key as another client",
+				"auth.clients 'some-client' dailyQuota must not be negative",
+				"auth.clients[2] must have a name",
+				"auth.clients[2] must have a key",
+				"auth.clients[2] burst must not be negative",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.modify(cfg)
+
+			err := cfg.Validate()
+			if tt.wantProblems == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			var invalid *ValidationError
+			require.ErrorAs(t, err, &invalid)
+			require.Equal(t, tt.wantProblems, invalid.Problems)
+		})
+	}
+}
+
+func TestValidationError_Error(t *testing.T) {
+	err := &ValidationError{Problems: []string{"serverPort is required", "cacheExpiry is required"}}
+	require.EqualError(t, err, "invalid config: serverPort is required; cacheExpiry is required")
+}
+
+func validConfig() *Config {
+	return &Config{
+		ServerPort:          8080,
+		CacheExpiry:         3 * time.Second,
+		ForecastCacheExpiry: 30 * time.Minute,
+		WeatherStackAPIKey:  "some-key",
+		OpenWeatherAPIKey:   "some-key",
+		LogLevel:            "info",
+		Tracing:             Tracing{Exporter: "otlp", Endpoint: "localhost:4317", SampleRatio: 1},
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
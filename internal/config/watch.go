@@ -0,0 +1,246 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/joshjon/sydneyweather/internal/logging"
+)
+
+// What triggered a reload.
+const (
+	TriggerFile    = "file"
+	TriggerSignal  = "signal"
+	TriggerRefresh = "refresh" // Secrets are due to be resolved again
+)
+
+// Reload statuses.
+const (
+	ReloadApplied   = "applied"   // Every change was applied
+	ReloadPartial   = "partial"   // Live changes were applied and the rest rejected
+	ReloadRejected  = "rejected"  // Every change requires a restart
+	ReloadUnchanged = "unchanged" // Nothing changed
+	ReloadFailed    = "failed"    // The config could not be read or is invalid
+)
+
+const (
+	defaultWatchInterval = 2 * time.Second
+	maxReloadResults     = 20
+)
+
+// ReloadResult is the outcome of a reload attempt. Changed fields are named by
+// their yaml key.
+type ReloadResult struct {
+	Time     time.Time `json:"time"`
+	Trigger  string    `json:"trigger"`
+	Status   string    `json:"status"`
+	Applied  []string  `json:"applied,omitempty"`
+	Rejected []string  `json:"rejected,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// ReloadsResponse lists the most recent config reload attempts, oldest first.
+type ReloadsResponse struct {
+	Reloads []ReloadResult `json:"reloads"`
+}
+
+// Watcher reloads the config when its file changes, the process receives
+// SIGHUP or secrets are due to be refreshed. Changes to live fields are passed
+// to apply, while changes to other fields are logged and rejected, keeping
+// their current value until restart. An invalid config is rejected entirely.
+type Watcher struct {
+	src      source
+	interval time.Duration
+	refresh  time.Duration
+	logger   *slog.Logger
+	apply    func(cfg *Config)
+
+	// reloadMu serializes reloads, while mu guards current and results so that
+	// they can be read while a reload waits on the config or its secrets.
+	reloadMu sync.Mutex
+	mu       sync.Mutex
+	current  *Config
+	results  []ReloadResult
+}
+
+// NewWatcher creates a watcher of the source that cfg was loaded from. Call Run
+// to start watching.
+func NewWatcher(cfg *Config, logger *slog.Logger, apply func(cfg *Config)) *Watcher {
+	refresh := cfg.SecretRefreshInterval
+	if refresh <= 0 {
+		refresh = defaultSecretRefresh
+	}
+
+	return &Watcher{
+		src:      cfg.src,
+		interval: defaultWatchInterval,
+		refresh:  refresh,
+		logger:   logger,
+		apply:    apply,
+		current:  cfg,
+	}
+}
+
+// Run reloads the config whenever the content of the config or clients file
+// changes, SIGHUP is received or the secret refresh interval passes until the
+// context is done. Files are polled rather than watched for events so that
+// they can be replaced, e.g. by a Kubernetes config map. Env vars and flags are
+// read again on reload but only secrets they refer to can change.
+func (w *Watcher) Run(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	refresh := time.NewTicker(w.refresh)
+	defer refresh.Stop()
+
+	last, _ := w.files()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			w.Reload(TriggerSignal)
+		case <-refresh.C:
+			w.Reload(TriggerRefresh)
+		case <-ticker.C:
+			// A file may be briefly missing while it is replaced
+			content, err := w.files()
+			if err != nil || bytes.Equal(content, last) {
+				continue
+			}
+			last = content
+			w.Reload(TriggerFile)
+		}
+	}
+}
+
+// files returns the content of the config file and the clients file it refers
+// to, which are both watched.
+func (w *Watcher) files() ([]byte, error) {
+	w.mu.Lock()
+	paths := []string{w.src.path, w.current.Auth.ClientsFile}
+	w.mu.Unlock()
+
+	var content []byte
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		content = append(append(content, b...), 0)
+	}
+	return content, nil
+}
+
+// Reload reads the config again and applies any live changes. Refreshes that
+// change nothing are not recorded so that they do not push out the results of
+// other reloads.
+func (w *Watcher) Reload(trigger string) ReloadResult {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+
+	res := ReloadResult{Time: time.Now().UTC(), Trigger: trigger}
+	logger := w.logger.With("trigger", trigger)
+
+	// current is only replaced while reloadMu is held
+	next, err := read(w.src)
+	if err != nil {
+		res.Status, res.Error = ReloadFailed, err.Error()
+		logger.Error("error reloading config", logging.KeyError, err)
+		w.record(res)
+		return res
+	}
+
+	res.Applied, res.Rejected = diff(w.current, next)
+	for _, field := range res.Rejected {
+		logger.Warn("config change requires a restart and was not applied", "field", field)
+	}
+
+	switch {
+	case len(res.Applied) == 0 && len(res.Rejected) == 0:
+		res.Status = ReloadUnchanged
+	case len(res.Applied) == 0:
+		res.Status = ReloadRejected
+	case len(res.Rejected) == 0:
+		res.Status = ReloadApplied
+	default:
+		res.Status = ReloadPartial
+	}
+
+	if len(res.Applied) > 0 {
+		w.apply(next)
+		w.mu.Lock()
+		w.current = next
+		w.mu.Unlock()
+		logger.Info("config reloaded", "applied", res.Applied)
+	}
+
+	if res.Status != ReloadUnchanged || trigger != TriggerRefresh {
+		w.record(res)
+	}
+	return res
+}
+
+// Results returns the most recent reload attempts, oldest first.
+func (w *Watcher) Results() []ReloadResult {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]ReloadResult{}, w.results...)
+}
+
+// GetReloads returns the most recent reload attempts, oldest first.
+func (w *Watcher) GetReloads(ctx echo.Context) error {
+	return ctx.JSON(http.StatusOK, &ReloadsResponse{Reloads: w.Results()})
+}
+
+func (w *Watcher) record(res ReloadResult) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.results = append(w.results, res)
+	if len(w.results) > maxReloadResults {
+		w.results = w.results[len(w.results)-maxReloadResults:]
+	}
+}
+
+// diff returns the changed fields of next that can be applied live and those
+// that cannot. Fields that cannot are reset in next to their current value.
+func diff(current *Config, next *Config) (applied []string, rejected []string) {
+	cur, nxt := reflect.ValueOf(current).Elem(), reflect.ValueOf(next).Elem()
+
+	for i := 0; i < cur.NumField(); i++ {
+		field := cur.Type().Field(i)
+		if !field.IsExported() || reflect.DeepEqual(cur.Field(i).Interface(), nxt.Field(i).Interface()) {
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if field.Tag.Get("reload") == "live" {
+			applied = append(applied, name)
+			continue
+		}
+
+		rejected = append(rejected, name)
+		nxt.Field(i).Set(cur.Field(i))
+	}
+
+	return applied, rejected
+}
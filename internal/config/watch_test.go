@@ -0,0 +1,216 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `
+serverPort: 8080
+cacheExpiry: 3s
+forecastCacheExpiry: 30m
+weatherStackAPIKey: some-key
+openWeatherAPIKey: some-key
+`
+
+func TestWatcher_Reload(t *testing.T) {
+	tests := []struct {
+		name         string
+		content      string
+		wantStatus   string
+		wantApplied  []string
+		wantRejected []string
+		wantApply    bool
+	}{
+		{
+			name:       "unchanged",
+			content:    testConfig,
+			wantStatus: ReloadUnchanged,
+		},
+		{
+			name:        "live changes",
+			content:     testConfig + "cacheExpiry: 1m\nproviders: [openweather]\nlogLevel: debug\n",
+			wantStatus:  ReloadApplied,
+			wantApplied: []string{"cacheExpiry", "providers", "logLevel"},
+			wantApply:   true,
+		},
+		{
+			name:        "auth changes",
+			content:     testConfig + "auth: { enabled: true, clients: [{ name: some-client, key: some-key, rateLimit: 5 }] }\n",
+			wantStatus:  ReloadApplied,
+			wantApplied: []string{"auth"},
+			wantApply:   true,
+		},
+		{
+			name:         "live and restart changes",
+			content:      testConfig + "serverPort: 9090\nproviderTimeout: 5s\n",
+			wantStatus:   ReloadPartial,
+			wantApplied:  []string{"providerTimeout"},
+			wantRejected: []string{"serverPort"},
+			wantApply:    true,
+		},
+		{
+			name:         "restart changes",
+			content:      testConfig + "serverPort: 9090\nhealth: { failureThreshold: 3 }\n",
+			wantStatus:   ReloadRejected,
+			wantRejected: []string{"serverPort", "health"},
+		},
+		{
+			name:       "invalid",
+			content:    testConfig + "cacheExpiry: -1s\n",
+			wantStatus: ReloadFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfig(t, testConfig)
+			cfg, err := read(source{path: path})
+			require.NoError(t, err)
+
+			var applied *Config
+			w := NewWatcher(cfg, discardLogger(), func(cfg *Config) { applied = cfg })
+
+			writeFile(t, path, tt.content)
+			res := w.Reload(TriggerSignal)
+
+			require.Equal(t, tt.wantStatus, res.Status)
+			require.Equal(t, TriggerSignal, res.Trigger)
+			require.Equal(t, tt.wantApplied, res.Applied)
+			require.Equal(t, tt.wantRejected, res.Rejected)
+			require.Equal(t, []ReloadResult{res}, w.Results())
+
+			if !tt.wantApply {
+				require.Nil(t, applied)
+				return
+			}
+			// Changes that require a restart keep their current value
+			require.Equal(t, 8080, applied.ServerPort)
+		})
+	}
+}
+
+func TestWatcher_Reload_failedError(t *testing.T) {
+	path := writeConfig(t, testConfig)
+	cfg, err := read(source{path: path})
+	require.NoError(t, err)
+
+	w := NewWatcher(cfg, discardLogger(), func(cfg *Config) {})
+	writeFile(t, path, testConfig+"cacheExpiry: -1s\n")
+
+	res := w.Reload(TriggerFile)
+	require.Equal(t, "invalid config: cacheExpiry must be positive", res.Error)
+}
+
+func TestWatcher_Results_limit(t *testing.T) {
+	path := writeConfig(t, testConfig)
+	cfg, err := read(source{path: path})
+	require.NoError(t, err)
+
+	w := NewWatcher(cfg, discardLogger(), func(cfg *Config) {})
+	for i := 0; i < maxReloadResults+5; i++ {
+		w.Reload(TriggerSignal)
+	}
+
+	require.Len(t, w.Results(), maxReloadResults)
+}
+
+func TestWatcher_Results_duringReload(t *testing.T) {
+	path := writeConfig(t, testConfig)
+	cfg, err := read(source{path: path})
+	require.NoError(t, err)
+
+	applying, release := make(chan struct{}), make(chan struct{})
+	w := NewWatcher(cfg, discardLogger(), func(cfg *Config) {
+		close(applying)
+		<-release
+	})
+	writeFile(t, path, testConfig+"cacheExpiry: 5s\n")
+
+	done := make(chan ReloadResult)
+	go func() { done <- w.Reload(TriggerFile) }()
+
+	<-applying
+	require.Empty(t, w.Results())
+	close(release)
+	require.Equal(t, ReloadApplied, (<-done).Status)
+	require.Len(t, w.Results(), 1)
+}
+
+func TestWatcher_GetReloads(t *testing.T) {
+	path := writeConfig(t, testConfig)
+	cfg, err := read(source{path: path})
+	require.NoError(t, err)
+
+	w := NewWatcher(cfg, discardLogger(), func(cfg *Config) {})
+	res := w.Reload(TriggerSignal)
+
+	e := echo.New()
+	e.GET("/config/reloads", w.GetReloads)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config/reloads", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp ReloadsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Reloads, 1)
+	require.Equal(t, res.Status, resp.Reloads[0].Status)
+	require.Equal(t, TriggerSignal, resp.Reloads[0].Trigger)
+}
+
+func TestWatcher_Run(t *testing.T) {
+	path := writeConfig(t, testConfig)
+	cfg, err := read(source{path: path})
+	require.NoError(t, err)
+
+	applied := make(chan *Config, 1)
+	w := NewWatcher(cfg, discardLogger(), func(cfg *Config) { applied <- cfg })
+	w.interval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.Run(ctx)
+	}()
+
+	// Wait for the watcher to read the initial content
+	time.Sleep(50 * time.Millisecond)
+	writeFile(t, path, testConfig+"cacheExpiry: 1m\n")
+
+	select {
+	case cfg := <-applied:
+		require.Equal(t, time.Minute, cfg.CacheExpiry)
+	case <-time.After(5 * time.Second):
+		t.Fatal("config change was not applied")
+	}
+
+	cancel()
+	<-done
+	require.Equal(t, TriggerFile, w.Results()[0].Trigger)
+}
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, content)
+	return path
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
@@ -0,0 +1,100 @@
+// Package logging provides the structured JSON logger of the service, which
+// redacts secrets, and carries request scoped loggers in contexts.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Attribute keys shared by log lines.
+const (
+	KeyRequestID = "request_id"
+	KeyClient    = "client"
+	KeyError     = "error"
+)
+
+const redacted = "REDACTED"
+
+// secretParam matches query params that carry provider API keys, which appear
+// in client errors that include the request URL.
+var secretParam = regexp.MustCompile(`(?i)\b(access_key|appid|api_?key|key|token)=[^&\s"]*`)
+
+// sensitiveKeys are the substrings of attribute keys, lower-cased and without
+// separators, whose values are never logged.
+var sensitiveKeys = []string{"apikey", "accesskey", "appid", "token", "secret", "password", "authorization"}
+
+type loggerKey struct{}
+
+// New creates a logger that writes JSON lines at or above the level to w. Pass
+// a *slog.LevelVar to change the level while the logger is in use.
+func New(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redactAttr,
+	}))
+}
+
+// ParseLevel parses a level name (debug, info, warn or error), which defaults
+// to info when empty.
+func ParseLevel(s string) (slog.Level, error) {
+	if s == "" {
+		return slog.LevelInfo, nil
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("invalid log level '%s'", s)
+	}
+	return level, nil
+}
+
+// WithLogger returns a copy of ctx that carries the logger.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger carried by ctx, otherwise the default logger.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// Redact removes API keys from a message.
+func Redact(msg string) string {
+	return secretParam.ReplaceAllString(msg, "${1}="+redacted)
+}
+
+// redactAttr hides the values of sensitive attributes and removes API keys from
+// string and error values.
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	if isSensitive(a.Key) {
+		return slog.String(a.Key, redacted)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, Redact(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, Redact(err.Error()))
+		}
+	}
+
+	return a
+}
+
+func isSensitive(key string) bool {
+	key = strings.NewReplacer("_", "", "-", "", ".", "").Replace(strings.ToLower(key))
+	for _, s := range sensitiveKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelInfo)
+
+	logger.Debug("some-debug-message")
+	logger.Info("some-message",
+		"weatherStackAPIKey", "some-key",
+		"Authorization", "Bearer some-token",
+		"url", "http://api.weatherstack.com/current?access_key=some-key&query=sydney",
+		KeyError, errors.New(`Get "https://api.openweathermap.org/data/2.5/weather?appid=some-key": EOF`),
+		"city", "sydney",
+	)
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	require.Equal(t, "INFO", got[slog.LevelKey])
+	require.Equal(t, "some-message", got[slog.MessageKey])
+	require.Equal(t, "REDACTED", got["weatherStackAPIKey"])
+	require.Equal(t, "REDACTED", got["Authorization"])
+	require.Equal(t, "http://api.weatherstack.com/current?access_key=REDACTED&query=sydney", got["url"])
+	require.Equal(t, `Get "https://api.openweathermap.org/data/2.5/weather?appid=REDACTED": EOF`, got[KeyError])
+	require.Equal(t, "sydney", got["city"])
+	require.NotContains(t, buf.String(), "some-key")
+	require.NotContains(t, buf.String(), "some-debug-message")
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		level   string
+		want    slog.Level
+		wantErr bool
+	}{
+		{level: "", want: slog.LevelInfo},
+		{level: "debug", want: slog.LevelDebug},
+		{level: "INFO", want: slog.LevelInfo},
+		{level: "warn", want: slog.LevelWarn},
+		{level: "error", want: slog.LevelError},
+		{level: "verbose", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			got, err := ParseLevel(tt.level)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	require.Equal(t, slog.Default(), FromContext(context.Background()))
+
+	logger := New(&bytes.Buffer{}, slog.LevelInfo)
+	require.Equal(t, logger, FromContext(WithLogger(context.Background(), logger)))
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want string
+	}{
+		{
+			msg:  `Get "http://api.weatherstack.com/current?access_key=secret&query=sydney": dial tcp: i/o timeout`,
+			want: `Get "http://api.weatherstack.com/current?access_key=REDACTED&query=sydney": dial tcp: i/o timeout`,
+		},
+		{
+			msg:  `Get "https://api.openweathermap.org/data/2.5/weather?appid=secret&q=sydney&units=metric": EOF`,
+			want: `Get "https://api.openweathermap.org/data/2.5/weather?appid=REDACTED&q=sydney&units=metric": EOF`,
+		},
+		{
+			msg:  "http error; status code: 401",
+			want: "http error; status code: 401",
+		},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.want, Redact(tt.msg))
+	}
+}
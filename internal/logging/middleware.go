@@ -0,0 +1,58 @@
+package logging
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Middleware adds a logger with the request ID to the request context and logs
+// each request once it completes. The request is logged with the request
+// context's logger once handled, which carries any attributes added by inner
+// middleware such as the authenticated client. It must be used after the
+// request ID middleware. Server errors are logged at error level and client errors at
+// warn level.
+func Middleware(logger *slog.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			start := time.Now()
+			req := ctx.Request()
+
+			requestLogger := logger.With(KeyRequestID, ctx.Response().Header().Get(echo.HeaderXRequestID))
+			ctx.SetRequest(req.WithContext(WithLogger(req.Context(), requestLogger)))
+
+			// Errors are handled here so that their status codes are logged
+			err := next(ctx)
+			if err != nil {
+				ctx.Error(err)
+			}
+
+			status := ctx.Response().Status
+			level := slog.LevelInfo
+			if status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			} else if status >= http.StatusBadRequest {
+				level = slog.LevelWarn
+			}
+
+			attrs := []slog.Attr{
+				slog.String("method", req.Method),
+				slog.String("uri", req.RequestURI),
+				slog.String("route", ctx.Path()),
+				slog.Int("status", status),
+				slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+				slog.Int64("bytes_out", ctx.Response().Size),
+				slog.String("remote_ip", ctx.RealIP()),
+				slog.String("user_agent", req.UserAgent()),
+			}
+			if err != nil {
+				attrs = append(attrs, slog.Any(KeyError, err))
+			}
+			FromContext(ctx.Request().Context()).LogAttrs(req.Context(), level, "request", attrs...)
+
+			return nil
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name      string
+		handler   echo.HandlerFunc
+		wantCode  int
+		wantLevel string
+	}{
+		{
+			name: "success",
+			handler: func(ctx echo.Context) error {
+				FromContext(ctx.Request().Context()).Info("some-message")
+				return ctx.NoContent(http.StatusOK)
+			},
+			wantCode:  http.StatusOK,
+			wantLevel: "INFO",
+		},
+		{
+			name: "client error",
+			handler: func(ctx echo.Context) error {
+				FromContext(ctx.Request().Context()).Info("some-message")
+				return echo.NewHTTPError(http.StatusBadRequest)
+			},
+			wantCode:  http.StatusBadRequest,
+			wantLevel: "WARN",
+		},
+		{
+			name: "server error",
+			handler: func(ctx echo.Context) error {
+				FromContext(ctx.Request().Context()).Info("some-message")
+				return echo.NewHTTPError(http.StatusServiceUnavailable)
+			},
+			wantCode:  http.StatusServiceUnavailable,
+			wantLevel: "ERROR",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			e := echo.New()
+			e.Use(middleware.RequestID(), Middleware(New(&buf, slog.LevelInfo)))
+			e.GET("/v1/weather", tt.handler)
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/weather?city=sydney", nil))
+			require.Equal(t, tt.wantCode, rec.Code)
+
+			requestID := rec.Header().Get(echo.HeaderXRequestID)
+			require.NotEmpty(t, requestID)
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			require.Len(t, lines, 2)
+
+			var handlerLine, requestLine map[string]any
+			require.NoError(t, json.Unmarshal([]byte(lines[0]), &handlerLine))
+			require.NoError(t, json.Unmarshal([]byte(lines[1]), &requestLine))
+
+			require.Equal(t, "some-message", handlerLine[slog.MessageKey])
+			require.Equal(t, requestID, handlerLine[KeyRequestID])
+
+			require.Equal(t, "request", requestLine[slog.MessageKey])
+			require.Equal(t, tt.wantLevel, requestLine[slog.LevelKey])
+			require.Equal(t, requestID, requestLine[KeyRequestID])
+			require.Equal(t, "/v1/weather", requestLine["route"])
+			require.Equal(t, "/v1/weather?city=sydney", requestLine["uri"])
+			require.Equal(t, float64(tt.wantCode), requestLine["status"])
+		})
+	}
+}
+
+func TestMiddleware_innerAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	e := echo.New()
+	e.Use(middleware.RequestID(), Middleware(New(&buf, slog.LevelInfo)))
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			req := ctx.Request()
+			ctx.SetRequest(req.WithContext(WithLogger(req.Context(), FromContext(req.Context()).With(KeyClient, "some-client"))))
+			return next(ctx)
+		}
+	})
+	e.GET("/v1/weather", func(ctx echo.Context) error { return ctx.NoContent(http.StatusOK) })
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/weather", nil))
+
+	var requestLine map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &requestLine))
+	require.Equal(t, "request", requestLine[slog.MessageKey])
+	require.Equal(t, rec.Header().Get(echo.HeaderXRequestID), requestLine[KeyRequestID])
+	require.Equal(t, "some-client", requestLine[KeyClient])
+}
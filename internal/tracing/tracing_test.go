@@ -0,0 +1,98 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "default", cfg: Config{}},
+		{name: "none", cfg: Config{Exporter: ExporterNone}},
+		{name: "stdout", cfg: Config{ServiceName: "some-service", Exporter: ExporterStdout, SampleRatio: 1}},
+		{name: "otlp", cfg: Config{ServiceName: "some-service", Exporter: ExporterOTLP, Endpoint: "localhost:4317", Insecure: true}},
+		{name: "unknown", cfg: Config{Exporter: "some-exporter"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prev := otel.GetTracerProvider()
+			defer otel.SetTracerProvider(prev)
+
+			shutdown, err := Setup(context.Background(), tt.cfg)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, shutdown(context.Background()))
+		})
+	}
+}
+
+func TestSetup_sampleRatio(t *testing.T) {
+	prev := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(prev)
+
+	for _, ratio := range []float64{0, 1} {
+		shutdown, err := Setup(context.Background(), Config{Exporter: ExporterStdout, SampleRatio: ratio})
+		require.NoError(t, err)
+
+		_, span := otel.Tracer("some-tracer").Start(context.Background(), "some-span")
+		require.Equal(t, ratio == 1, span.SpanContext().IsSampled())
+		span.End()
+		require.NoError(t, shutdown(context.Background()))
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	}()
+
+	e := echo.New()
+	e.Use(middleware.RequestID(), Middleware())
+	e.GET("/v1/weather", func(ctx echo.Context) error {
+		return echo.NewHTTPError(http.StatusServiceUnavailable)
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/v1/weather?city=sydney", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	require.Equal(t, "GET /v1/weather", span.Name())
+	require.Equal(t, traceID, span.SpanContext().TraceID().String())
+	require.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+	require.True(t, span.Parent().IsRemote())
+	require.Equal(t, codes.Error, span.Status().Code)
+	require.Contains(t, span.Attributes(), semconv.HTTPRouteKey.String("/v1/weather"))
+	require.Contains(t, span.Attributes(), semconv.HTTPStatusCodeKey.Int(http.StatusServiceUnavailable))
+	require.Contains(t, span.Attributes(), attrRequestID.String(rec.Header().Get(echo.HeaderXRequestID)))
+}
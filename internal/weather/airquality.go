@@ -0,0 +1,174 @@
+package weather
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// AirQualityCategory is the US EPA category for an air quality index value.
+type AirQualityCategory string
+
+const (
+	AirQualityUnknown                     AirQualityCategory = "unknown"
+	AirQualityGood                        AirQualityCategory = "good"
+	AirQualityModerate                    AirQualityCategory = "moderate"
+	AirQualityUnhealthyForSensitiveGroups AirQualityCategory = "unhealthy_for_sensitive_groups"
+	AirQualityUnhealthy                   AirQualityCategory = "unhealthy"
+	AirQualityVeryUnhealthy               AirQualityCategory = "very_unhealthy"
+	AirQualityHazardous                   AirQualityCategory = "hazardous"
+)
+
+// Molar volume based factors for converting μg/m3 to ppb at 25°C and 1 atm.
+const (
+	o3MicrogramsPerPPB  = 1.96
+	no2MicrogramsPerPPB = 1.88
+)
+
+// ErrAirQualityNotFound is returned when a provider response does not contain
+// an air quality measurement.
+var ErrAirQualityNotFound = errors.New("air quality not found")
+
+// AirQuality is a pollutant measurement normalised across providers.
+// Concentrations are in μg/m3 and are nil when not reported. AQI is the US EPA
+// air quality index computed from the concentrations rather than taken from the
+// provider, since providers use different scales.
+type AirQuality struct {
+	Time     time.Time
+	AQI      *int
+	Category AirQualityCategory
+	PM25     *float64
+	PM10     *float64
+	O3       *float64
+	NO2      *float64
+}
+
+// AirQuality normalises the OpenWeather air pollution response.
+func (r *OpenWeatherAirPollutionResponse) AirQuality(zone *time.Location) (AirQuality, error) {
+	if len(r.List) == 0 {
+		return AirQuality{}, ErrAirQualityNotFound
+	}
+
+	m := r.List[0]
+	return NewAirQuality(time.Unix(m.Dt, 0).In(zone), m.Components.PM25, m.Components.PM10, m.Components.O3, m.Components.NO2), nil
+}
+
+// NewAirQuality creates an air quality measurement and computes its index and
+// category from the pollutant concentrations in μg/m3.
+func NewAirQuality(t time.Time, pm25 *float64, pm10 *float64, o3 *float64, no2 *float64) AirQuality {
+	aq := AirQuality{
+		Time:     t,
+		Category: AirQualityUnknown,
+		PM25:     pm25,
+		PM10:     pm10,
+		O3:       o3,
+		NO2:      no2,
+	}
+
+	if index, ok := airQualityIndex(pm25, pm10, o3, no2); ok {
+		aq.AQI = &index
+		aq.Category = airQualityCategory(index)
+	}
+
+	return aq
+}
+
+// aqiBreakpoint maps a pollutant concentration range to an index range.
+type aqiBreakpoint struct {
+	cLow, cHigh float64
+	iLow, iHigh int
+}
+
+// EPA breakpoints. See https://www.airnow.gov/sites/default/files/2020-05/aqi-technical-assistance-document-sept2018.pdf
+// Measurements are instantaneous, so they are used in place of the averaging
+// periods the breakpoints are defined for.
+var (
+	pm25Breakpoints = []aqiBreakpoint{ // μg/m3, 24 hour
+		{0, 12, 0, 50},
+		{12.1, 35.4, 51, 100},
+		{35.5, 55.4, 101, 150},
+		{55.5, 150.4, 151, 200},
+		{150.5, 250.4, 201, 300},
+		{250.5, 350.4, 301, 400},
+		{350.5, 500.4, 401, 500},
+	}
+	pm10Breakpoints = []aqiBreakpoint{ // μg/m3, 24 hour
+		{0, 54, 0, 50},
+		{55, 154, 51, 100},
+		{155, 254, 101, 150},
+		{255, 354, 151, 200},
+		{355, 424, 201, 300},
+		{425, 504, 301, 400},
+		{505, 604, 401, 500},
+	}
+	o3Breakpoints = []aqiBreakpoint{ // ppb, 8 hour
+		{0, 54, 0, 50},
+		{55, 70, 51, 100},
+		{71, 85, 101, 150},
+		{86, 105, 151, 200},
+		{106, 200, 201, 300},
+	}
+	no2Breakpoints = []aqiBreakpoint{ // ppb, 1 hour
+		{0, 53, 0, 50},
+		{54, 100, 51, 100},
+		{101, 360, 101, 150},
+		{361, 649, 151, 200},
+		{650, 1249, 201, 300},
+		{1250, 1649, 301, 400},
+		{1650, 2049, 401, 500},
+	}
+)
+
+// airQualityIndex returns the overall index, which is the highest index of the
+// individual pollutants. False is returned if no pollutants are reported.
+func airQualityIndex(pm25 *float64, pm10 *float64, o3 *float64, no2 *float64) (int, bool) {
+	index, ok := 0, false
+	include := func(concentration *float64, truncate func(float64) float64, breakpoints []aqiBreakpoint) {
+		if concentration == nil {
+			return
+		}
+		i := pollutantIndex(truncate(*concentration), breakpoints)
+		if !ok || i > index {
+			index, ok = i, true
+		}
+	}
+
+	include(pm25, func(c float64) float64 { return math.Floor(c*10) / 10 }, pm25Breakpoints)
+	include(pm10, math.Floor, pm10Breakpoints)
+	include(o3, func(c float64) float64 { return math.Floor(c / o3MicrogramsPerPPB) }, o3Breakpoints)
+	include(no2, func(c float64) float64 { return math.Floor(c / no2MicrogramsPerPPB) }, no2Breakpoints)
+
+	return index, ok
+}
+
+// pollutantIndex linearly interpolates the concentration within its breakpoint.
+// Concentrations above the highest breakpoint are capped at its index.
+func pollutantIndex(concentration float64, breakpoints []aqiBreakpoint) int {
+	for _, bp := range breakpoints {
+		if concentration <= bp.cHigh {
+			if concentration < bp.cLow {
+				concentration = bp.cLow
+			}
+			i := float64(bp.iHigh-bp.iLow)/(bp.cHigh-bp.cLow)*(concentration-bp.cLow) + float64(bp.iLow)
+			return int(math.Round(i))
+		}
+	}
+	return breakpoints[len(breakpoints)-1].iHigh
+}
+
+func airQualityCategory(index int) AirQualityCategory {
+	switch {
+	case index <= 50:
+		return AirQualityGood
+	case index <= 100:
+		return AirQualityModerate
+	case index <= 150:
+		return AirQualityUnhealthyForSensitiveGroups
+	case index <= 200:
+		return AirQualityUnhealthy
+	case index <= 300:
+		return AirQualityVeryUnhealthy
+	default:
+		return AirQualityHazardous
+	}
+}
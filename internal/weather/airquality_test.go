@@ -0,0 +1,93 @@
+package weather
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAirQuality(t *testing.T) {
+	tests := []struct {
+		name         string
+		pm25         *float64
+		pm10         *float64
+		o3           *float64
+		no2          *float64
+		wantAQI      *int
+		wantCategory AirQualityCategory
+	}{
+		{
+			name:         "good",
+			pm25:         ptr(5.0),
+			pm10:         ptr(20.0),
+			wantAQI:      ptr(21),
+			wantCategory: AirQualityGood,
+		},
+		{
+			name:         "pm2.5 unhealthy for sensitive groups", // EPA worked example
+			pm25:         ptr(35.9),
+			pm10:         ptr(20.0),
+			wantAQI:      ptr(102),
+			wantCategory: AirQualityUnhealthyForSensitiveGroups,
+		},
+		{
+			name:         "o3 dominant", // 78 ppb
+			pm25:         ptr(5.0),
+			o3:           ptr(153.0),
+			wantAQI:      ptr(126),
+			wantCategory: AirQualityUnhealthyForSensitiveGroups,
+		},
+		{
+			name:         "no2 moderate", // 100 ppb
+			no2:          ptr(188.0),
+			wantAQI:      ptr(100),
+			wantCategory: AirQualityModerate,
+		},
+		{
+			name:         "above highest breakpoint",
+			pm10:         ptr(700.0),
+			wantAQI:      ptr(500),
+			wantCategory: AirQualityHazardous,
+		},
+		{
+			name:         "no pollutants",
+			wantCategory: AirQualityUnknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aq := NewAirQuality(time.Time{}, tt.pm25, tt.pm10, tt.o3, tt.no2)
+			require.Equal(t, tt.wantAQI, aq.AQI)
+			require.Equal(t, tt.wantCategory, aq.Category)
+		})
+	}
+}
+
+func TestOpenWeatherAirPollutionResponse_AirQuality(t *testing.T) {
+	resp := OpenWeatherAirPollutionResponse{
+		List: []OpenWeatherAirPollution{
+			{
+				Dt:   1651363200,
+				Main: OpenWeatherAirPollutionMain{AQI: 1},
+				Components: OpenWeatherAirPollutionComponents{
+					PM25: ptr(5.0),
+					PM10: ptr(20.0),
+					O3:   ptr(40.0),
+					NO2:  ptr(10.0),
+				},
+			},
+		},
+	}
+
+	aq, err := resp.AirQuality(time.UTC)
+	require.NoError(t, err)
+	require.Equal(t, time.Unix(1651363200, 0).UTC(), aq.Time)
+	require.Equal(t, ptr(21), aq.AQI)
+	require.Equal(t, AirQualityGood, aq.Category)
+	require.Equal(t, ptr(40.0), aq.O3)
+
+	_, err = (&OpenWeatherAirPollutionResponse{}).AirQuality(time.UTC)
+	require.ErrorIs(t, err, ErrAirQualityNotFound)
+}
@@ -0,0 +1,80 @@
+package weather
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Severity is the level of threat posed by the hazard an alert warns of.
+type Severity string
+
+const (
+	SeverityUnknown  Severity = "unknown"
+	SeverityMinor    Severity = "minor"
+	SeverityModerate Severity = "moderate"
+	SeveritySevere   Severity = "severe"
+	SeverityExtreme  Severity = "extreme"
+)
+
+// Alert is a government issued weather warning normalised across providers.
+type Alert struct {
+	Headline    string
+	Description string
+	Source      string
+	Severity    Severity
+	Start       time.Time
+	End         time.Time
+	Tags        []string
+}
+
+// ID returns an identifier that is stable across provider responses. Alerts do
+// not have an upstream identifier, so the source, headline and start time are
+// used.
+func (a *Alert) ID() string {
+	h := sha1.New()
+	h.Write([]byte(a.Source))
+	h.Write([]byte{0})
+	h.Write([]byte(a.Headline))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.FormatInt(a.Start.Unix(), 10)))
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// Alerts normalises the alerts in the OpenWeather One Call response.
+func (r *OpenWeatherOneCallResponse) Alerts(zone *time.Location) []Alert {
+	alerts := make([]Alert, 0, len(r.Warnings))
+	for _, a := range r.Warnings {
+		alerts = append(alerts, Alert{
+			Headline:    a.Event,
+			Description: a.Description,
+			Source:      a.SenderName,
+			Severity:    severityFromEvent(a.Event),
+			Start:       time.Unix(a.Start, 0).In(zone),
+			End:         time.Unix(a.End, 0).In(zone),
+			Tags:        a.Tags,
+		})
+	}
+	return alerts
+}
+
+// severityFromEvent infers the severity of an alert from its event name since
+// OpenWeather passes on the issuing agency's wording rather than a severity,
+// e.g. 'Severe Thunderstorm Warning' or 'Flood Watch'.
+func severityFromEvent(event string) Severity {
+	event = strings.ToLower(event)
+	switch {
+	case strings.Contains(event, "extreme") || strings.Contains(event, "emergency"):
+		return SeverityExtreme
+	case strings.Contains(event, "severe"):
+		return SeveritySevere
+	case strings.Contains(event, "warning"):
+		return SeverityModerate
+	case strings.Contains(event, "watch") || strings.Contains(event, "advisory"):
+		return SeverityMinor
+	default:
+		return SeverityUnknown
+	}
+}
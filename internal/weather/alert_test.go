@@ -0,0 +1,66 @@
+package weather
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenWeatherOneCallResponse_Alerts(t *testing.T) {
+	zone := time.FixedZone("AEST", 10*60*60)
+	start := time.Date(2022, 5, 1, 9, 0, 0, 0, zone)
+	end := start.Add(6 * time.Hour)
+
+	resp := OpenWeatherOneCallResponse{
+		Warnings: []OpenWeatherAlert{
+			{
+				SenderName:  "Australian Government Bureau of Meteorology",
+				Event:       "Severe Thunderstorm Warning",
+				Start:       start.Unix(),
+				End:         end.Unix(),
+				Description: "some-description",
+				Tags:        []string{"Thunderstorm"},
+			},
+		},
+	}
+
+	alerts := resp.Alerts(zone)
+	require.Len(t, alerts, 1)
+	require.Equal(t, Alert{
+		Headline:    "Severe Thunderstorm Warning",
+		Description: "some-description",
+		Source:      "Australian Government Bureau of Meteorology",
+		Severity:    SeveritySevere,
+		Start:       start,
+		End:         end,
+		Tags:        []string{"Thunderstorm"},
+	}, alerts[0])
+}
+
+func TestAlert_ID(t *testing.T) {
+	start := time.Unix(1651363200, 0)
+	a := Alert{Source: "some-source", Headline: "Flood Watch", Start: start, Description: "v1"}
+	b := Alert{Source: "some-source", Headline: "Flood Watch", Start: start, Description: "v2"}
+	c := Alert{Source: "some-source", Headline: "Flood Watch", Start: start.Add(time.Hour)}
+
+	require.Equal(t, a.ID(), b.ID())
+	require.NotEqual(t, a.ID(), c.ID())
+}
+
+func TestSeverityFromEvent(t *testing.T) {
+	tests := []struct {
+		event string
+		want  Severity
+	}{
+		{event: "Extreme Heat Warning", want: SeverityExtreme},
+		{event: "Severe Weather Warning", want: SeveritySevere},
+		{event: "Fire Weather Warning", want: SeverityModerate},
+		{event: "Flood Watch", want: SeverityMinor},
+		{event: "Marine Wind", want: SeverityUnknown},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.want, severityFromEvent(tt.event), tt.event)
+	}
+}
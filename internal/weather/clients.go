@@ -1,8 +1,14 @@
 package weather
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-resty/resty/v2"
 )
@@ -17,52 +23,163 @@ const (
 // authenticate on each request.
 type WeatherStackClient struct {
 	http   *resty.Client
+	mu     sync.RWMutex
 	apiKey string
 }
 
 func NewWeatherStackClient(apiKey string) *WeatherStackClient {
-	client := newRestyClient(weatherStackBaseURL)
+	client := newRestyClient("weatherstack", weatherStackBaseURL)
 	return &WeatherStackClient{
 		http:   client,
 		apiKey: apiKey,
 	}
 }
 
+// SetAPIKey replaces the API key used by subsequent requests.
+func (c *WeatherStackClient) SetAPIKey(apiKey string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.apiKey = apiKey
+}
+
+func (c *WeatherStackClient) key() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.apiKey
+}
+
 // GetWeather returns the temperature and wind speed for the specified city.
-func (c *WeatherStackClient) GetWeather(city string) (*WeatherStackResponse, error) {
+func (c *WeatherStackClient) GetWeather(ctx context.Context, city string) (*WeatherStackResponse, error) {
 	req := c.http.R().
-		SetQueryParam("access_key", c.apiKey).
+		SetContext(ctx).
+		SetQueryParam("access_key", c.key()).
 		SetQueryParam("units", "m"). // Celsius
 		SetQueryParam("query", city).
 		SetResult(WeatherStackResponse{})
 	return get[WeatherStackResponse, WeatherStackErrorResponse](req, "/current")
 }
 
+// GetForecast returns the hourly and daily forecast for the specified city.
+func (c *WeatherStackClient) GetForecast(ctx context.Context, city string) (*WeatherStackForecastResponse, error) {
+	req := c.http.R().
+		SetContext(ctx).
+		SetQueryParam("access_key", c.key()).
+		SetQueryParam("units", "m"). // Celsius
+		SetQueryParam("query", city).
+		SetQueryParam("forecast_days", strconv.Itoa(ForecastDays)).
+		SetQueryParam("hourly", "1").
+		SetQueryParam("interval", "1"). // Hourly periods
+		SetResult(WeatherStackForecastResponse{})
+	return get[WeatherStackForecastResponse, WeatherStackErrorResponse](req, "/forecast")
+}
+
+// GetHistory returns the hourly historical weather for the specified city on
+// the local date of t.
+func (c *WeatherStackClient) GetHistory(ctx context.Context, city string, t time.Time) (*WeatherStackHistoryResponse, error) {
+	req := c.http.R().
+		SetContext(ctx).
+		SetQueryParam("access_key", c.key()).
+		SetQueryParam("units", "m"). // Celsius
+		SetQueryParam("query", city).
+		SetQueryParam("historical_date", t.Format("2006-01-02")).
+		SetQueryParam("hourly", "1").
+		SetQueryParam("interval", "1"). // Hourly periods
+		SetResult(WeatherStackHistoryResponse{})
+	return get[WeatherStackHistoryResponse, WeatherStackErrorResponse](req, "/historical")
+}
+
 // OpenWeatherClient is a simple client for retrieving basic weather data from
 // the OpenWeather API. A valid API key must be provided in order to successfully
 // authenticate on each request.
 type OpenWeatherClient struct {
 	http   *resty.Client
+	mu     sync.RWMutex
 	apiKey string
 }
 
 func NewOpenWeatherClient(apiKey string) *OpenWeatherClient {
 	return &OpenWeatherClient{
-		http:   newRestyClient(openWeatherBaseURL),
+		http:   newRestyClient("openweather", openWeatherBaseURL),
 		apiKey: apiKey,
 	}
 }
 
+// SetAPIKey replaces the API key used by subsequent requests.
+func (c *OpenWeatherClient) SetAPIKey(apiKey string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.apiKey = apiKey
+}
+
+func (c *OpenWeatherClient) key() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.apiKey
+}
+
 // GetWeather returns the temperature and wind speed for the specified city.
-func (c *OpenWeatherClient) GetWeather(city string) (*OpenWeatherResponse, error) {
+func (c *OpenWeatherClient) GetWeather(ctx context.Context, city string) (*OpenWeatherResponse, error) {
 	req := c.http.R().
-		SetQueryParam("appid", c.apiKey).
+		SetContext(ctx).
+		SetQueryParam("appid", c.key()).
 		SetQueryParam("units", "metric"). // Celsius
 		SetQueryParam("q", city).
 		SetResult(&OpenWeatherResponse{})
 	return get[OpenWeatherResponse, OpenWeatherErrorResponse](req, "/data/2.5/weather")
 }
 
+// GetForecast returns the hourly and daily forecast for the specified
+// coordinates.
+func (c *OpenWeatherClient) GetForecast(ctx context.Context, lat float64, lon float64) (*OpenWeatherOneCallResponse, error) {
+	return c.oneCall(ctx, lat, lon, "current", "minutely", "alerts")
+}
+
+// GetAlerts returns the government issued weather alerts for the specified
+// coordinates.
+func (c *OpenWeatherClient) GetAlerts(ctx context.Context, lat float64, lon float64) (*OpenWeatherOneCallResponse, error) {
+	return c.oneCall(ctx, lat, lon, "current", "minutely", "hourly", "daily")
+}
+
+// GetAirPollution returns the current air pollution for the specified
+// coordinates.
+func (c *OpenWeatherClient) GetAirPollution(ctx context.Context, lat float64, lon float64) (*OpenWeatherAirPollutionResponse, error) {
+	req := c.http.R().
+		SetContext(ctx).
+		SetQueryParam("appid", c.key()).
+		SetQueryParam("lat", formatCoord(lat)).
+		SetQueryParam("lon", formatCoord(lon)).
+		SetResult(&OpenWeatherAirPollutionResponse{})
+	return get[OpenWeatherAirPollutionResponse, OpenWeatherErrorResponse](req, "/data/2.5/air_pollution")
+}
+
+// GetHistory returns the historical weather for the specified coordinates at
+// time t.
+func (c *OpenWeatherClient) GetHistory(ctx context.Context, lat float64, lon float64, t time.Time) (*OpenWeatherTimeMachineResponse, error) {
+	req := c.http.R().
+		SetContext(ctx).
+		SetQueryParam("appid", c.key()).
+		SetQueryParam("units", "metric"). // Celsius
+		SetQueryParam("lat", formatCoord(lat)).
+		SetQueryParam("lon", formatCoord(lon)).
+		SetQueryParam("dt", strconv.FormatInt(t.Unix(), 10)).
+		SetResult(&OpenWeatherTimeMachineResponse{})
+	return get[OpenWeatherTimeMachineResponse, OpenWeatherErrorResponse](req, "/data/3.0/onecall/timemachine")
+}
+
+// oneCall performs a One Call API request for the specified coordinates. Parts
+// of the response that are not required can be excluded.
+func (c *OpenWeatherClient) oneCall(ctx context.Context, lat float64, lon float64, exclude ...string) (*OpenWeatherOneCallResponse, error) {
+	req := c.http.R().
+		SetContext(ctx).
+		SetQueryParam("appid", c.key()).
+		SetQueryParam("units", "metric"). // Celsius
+		SetQueryParam("lat", formatCoord(lat)).
+		SetQueryParam("lon", formatCoord(lon)).
+		SetQueryParam("exclude", strings.Join(exclude, ",")).
+		SetResult(&OpenWeatherOneCallResponse{})
+	return get[OpenWeatherOneCallResponse, OpenWeatherErrorResponse](req, "/data/3.0/onecall")
+}
+
 // get performs a GET request to the specified URL and returns the response.
 // The R and E type parameters are used when unmarshalling any response or error
 // body.
@@ -85,18 +202,31 @@ func get[R any, E any](req *resty.Request, url string) (*R, error) {
 
 }
 
-func newRestyClient(baseURL string) *resty.Client {
+func newRestyClient(provider string, baseURL string) *resty.Client {
 	return resty.New().
+		SetTransport(&tracingTransport{provider: provider, base: http.DefaultTransport}).
 		SetBaseURL(baseURL).
 		SetHeader("Content-Type", "application/json")
 }
 
-func newHTTPError(code int, err any) error {
-	if err != nil {
-		return fmt.Errorf("http error; status code: %d; error: %+v",
-			code,
-			err,
-		)
+func formatCoord(coord float64) string {
+	return strconv.FormatFloat(coord, 'f', -1, 64)
+}
+
+// HTTPError is returned when a provider responds with a non 2xx status. Body is
+// the decoded error response, or nil if it could not be decoded.
+type HTTPError struct {
+	StatusCode int
+	Body       any
+}
+
+func (e *HTTPError) Error() string {
+	if e.Body != nil {
+		return fmt.Sprintf("http error; status code: %d; error: %+v", e.StatusCode, e.Body)
 	}
-	return fmt.Errorf("http error; status code: %d", code)
+	return fmt.Sprintf("http error; status code: %d", e.StatusCode)
+}
+
+func newHTTPError(code int, body any) error {
+	return &HTTPError{StatusCode: code, Body: body}
 }
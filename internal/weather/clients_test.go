@@ -1,12 +1,15 @@
 package weather
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"reflect"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -14,6 +17,8 @@ import (
 const (
 	wantCity   = "Sydney"
 	wantAPIKey = "some-key"
+	wantLat    = -33.8688
+	wantLon    = 151.2093
 )
 
 func TestNewWeatherStackClient(t *testing.T) {
@@ -22,6 +27,20 @@ func TestNewWeatherStackClient(t *testing.T) {
 	require.Equal(t, weatherStackBaseURL, client.http.BaseURL)
 }
 
+func TestWeatherStackClient_SetAPIKey(t *testing.T) {
+	wantURLValues := weatherStackURLValues("other-key")
+	srv := mockServer(t, "/current", wantURLValues, http.StatusOK, WeatherStackResponse{})
+	defer srv.Close()
+
+	client := WeatherStackClient{
+		http:   newRestyClient("some-provider", srv.URL),
+		apiKey: wantAPIKey,
+	}
+	client.SetAPIKey("other-key")
+	_, err := client.GetWeather(context.Background(), wantCity)
+	require.NoError(t, err)
+}
+
 func TestWeatherStackClient_GetWeather(t *testing.T) {
 	wantResp := WeatherStackResponse{
 		Current: WeatherStackCurrent{
@@ -34,10 +53,10 @@ func TestWeatherStackClient_GetWeather(t *testing.T) {
 	defer srv.Close()
 
 	client := WeatherStackClient{
-		http:   newRestyClient(srv.URL),
+		http:   newRestyClient("some-provider", srv.URL),
 		apiKey: wantAPIKey,
 	}
-	resp, err := client.GetWeather(wantCity)
+	resp, err := client.GetWeather(context.Background(), wantCity)
 	require.NoError(t, err)
 	require.Equal(t, wantResp, *resp)
 }
@@ -73,10 +92,10 @@ func TestWeatherStackClient_GetWeather_error(t *testing.T) {
 			defer srv.Close()
 
 			client := WeatherStackClient{
-				http:   newRestyClient(srv.URL),
+				http:   newRestyClient("some-provider", srv.URL),
 				apiKey: wantAPIKey,
 			}
-			resp, err := client.GetWeather(wantCity)
+			resp, err := client.GetWeather(context.Background(), wantCity)
 
 			if tt.wantErrResp != nil {
 				require.EqualError(t, err, newHTTPError(tt.wantCode, *tt.wantErrResp).Error())
@@ -109,10 +128,10 @@ func TestOpenWeatherClient_GetWeather(t *testing.T) {
 	defer srv.Close()
 
 	client := OpenWeatherClient{
-		http:   newRestyClient(srv.URL),
+		http:   newRestyClient("some-provider", srv.URL),
 		apiKey: wantAPIKey,
 	}
-	resp, err := client.GetWeather(wantCity)
+	resp, err := client.GetWeather(context.Background(), wantCity)
 	require.NoError(t, err)
 	require.Equal(t, wantResp, *resp)
 }
@@ -144,10 +163,10 @@ func TestOpenWeather_GetWeather_error(t *testing.T) {
 			defer srv.Close()
 
 			client := OpenWeatherClient{
-				http:   newRestyClient(srv.URL),
+				http:   newRestyClient("some-provider", srv.URL),
 				apiKey: wantAPIKey,
 			}
-			resp, err := client.GetWeather(wantCity)
+			resp, err := client.GetWeather(context.Background(), wantCity)
 
 			if tt.wantErrResp != nil {
 				require.EqualError(t, err, newHTTPError(tt.wantCode, *tt.wantErrResp).Error())
@@ -160,6 +179,142 @@ func TestOpenWeather_GetWeather_error(t *testing.T) {
 	}
 }
 
+func TestWeatherStackClient_GetForecast(t *testing.T) {
+	wantResp := WeatherStackForecastResponse{
+		Days: map[string]WeatherStackForecastDay{
+			"2022-05-01": {
+				Date:    "2022-05-01",
+				MinTemp: 10,
+				MaxTemp: 20,
+				Hourly:  []WeatherStackHourly{{Time: "0", Temperature: 12, WindSpeed: 5}},
+			},
+		},
+	}
+	wantURLValues := weatherStackURLValues(wantAPIKey)
+	wantURLValues.Set("forecast_days", "7")
+	wantURLValues.Set("hourly", "1")
+	wantURLValues.Set("interval", "1")
+	srv := mockServer(t, "/forecast", wantURLValues, http.StatusOK, wantResp)
+	defer srv.Close()
+
+	client := WeatherStackClient{
+		http:   newRestyClient("some-provider", srv.URL),
+		apiKey: wantAPIKey,
+	}
+	resp, err := client.GetForecast(context.Background(), wantCity)
+	require.NoError(t, err)
+	require.Equal(t, wantResp, *resp)
+}
+
+func TestOpenWeatherClient_GetForecast(t *testing.T) {
+	wantResp := OpenWeatherOneCallResponse{
+		Hourly: []OpenWeatherHourly{{Dt: 1651363200, Temp: 12, WindSpeed: 5}},
+		Daily:  []OpenWeatherDaily{{Dt: 1651363200, Temp: OpenWeatherDailyTemp{Min: 10, Max: 20}}},
+	}
+	wantURLValues := openWeatherOneCallURLValues(wantAPIKey, "current,minutely,alerts")
+	srv := mockServer(t, "/data/3.0/onecall", wantURLValues, http.StatusOK, wantResp)
+	defer srv.Close()
+
+	client := OpenWeatherClient{
+		http:   newRestyClient("some-provider", srv.URL),
+		apiKey: wantAPIKey,
+	}
+	resp, err := client.GetForecast(context.Background(), wantLat, wantLon)
+	require.NoError(t, err)
+	require.Equal(t, wantResp, *resp)
+}
+
+func TestWeatherStackClient_GetHistory(t *testing.T) {
+	wantResp := WeatherStackHistoryResponse{
+		Days: map[string]WeatherStackForecastDay{
+			"2022-05-01": {
+				Date:   "2022-05-01",
+				Hourly: []WeatherStackHourly{{Time: "1200", Temperature: 12, WindSpeed: 5}},
+			},
+		},
+	}
+	wantURLValues := weatherStackURLValues(wantAPIKey)
+	wantURLValues.Set("historical_date", "2022-05-01")
+	wantURLValues.Set("hourly", "1")
+	wantURLValues.Set("interval", "1")
+	srv := mockServer(t, "/historical", wantURLValues, http.StatusOK, wantResp)
+	defer srv.Close()
+
+	client := WeatherStackClient{
+		http:   newRestyClient("some-provider", srv.URL),
+		apiKey: wantAPIKey,
+	}
+	resp, err := client.GetHistory(context.Background(), wantCity, time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Equal(t, wantResp, *resp)
+}
+
+func TestOpenWeatherClient_GetAlerts(t *testing.T) {
+	wantResp := OpenWeatherOneCallResponse{
+		Warnings: []OpenWeatherAlert{{SenderName: "some-sender", Event: "Flood Watch", Start: 1651363200, End: 1651384800}},
+	}
+	wantURLValues := openWeatherOneCallURLValues(wantAPIKey, "current,minutely,hourly,daily")
+	srv := mockServer(t, "/data/3.0/onecall", wantURLValues, http.StatusOK, wantResp)
+	defer srv.Close()
+
+	client := OpenWeatherClient{
+		http:   newRestyClient("some-provider", srv.URL),
+		apiKey: wantAPIKey,
+	}
+	resp, err := client.GetAlerts(context.Background(), wantLat, wantLon)
+	require.NoError(t, err)
+	require.Equal(t, wantResp, *resp)
+}
+
+func TestOpenWeatherClient_GetAirPollution(t *testing.T) {
+	wantResp := OpenWeatherAirPollutionResponse{
+		List: []OpenWeatherAirPollution{
+			{
+				Dt:         1651363200,
+				Main:       OpenWeatherAirPollutionMain{AQI: 2},
+				Components: OpenWeatherAirPollutionComponents{PM25: ptr(5.0)},
+			},
+		},
+	}
+	wantURLValues := url.Values{}
+	wantURLValues.Set("appid", wantAPIKey)
+	wantURLValues.Set("lat", "-33.8688")
+	wantURLValues.Set("lon", "151.2093")
+	srv := mockServer(t, "/data/2.5/air_pollution", wantURLValues, http.StatusOK, wantResp)
+	defer srv.Close()
+
+	client := OpenWeatherClient{
+		http:   newRestyClient("some-provider", srv.URL),
+		apiKey: wantAPIKey,
+	}
+	resp, err := client.GetAirPollution(context.Background(), wantLat, wantLon)
+	require.NoError(t, err)
+	require.Equal(t, wantResp, *resp)
+}
+
+func TestOpenWeatherClient_GetHistory(t *testing.T) {
+	wantTime := time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC)
+	wantResp := OpenWeatherTimeMachineResponse{
+		Data: []OpenWeatherHourly{{Dt: wantTime.Unix(), Temp: 12, WindSpeed: 5}},
+	}
+	wantURLValues := url.Values{}
+	wantURLValues.Set("appid", wantAPIKey)
+	wantURLValues.Set("units", "metric")
+	wantURLValues.Set("lat", "-33.8688")
+	wantURLValues.Set("lon", "151.2093")
+	wantURLValues.Set("dt", strconv.FormatInt(wantTime.Unix(), 10))
+	srv := mockServer(t, "/data/3.0/onecall/timemachine", wantURLValues, http.StatusOK, wantResp)
+	defer srv.Close()
+
+	client := OpenWeatherClient{
+		http:   newRestyClient("some-provider", srv.URL),
+		apiKey: wantAPIKey,
+	}
+	resp, err := client.GetHistory(context.Background(), wantLat, wantLon, wantTime)
+	require.NoError(t, err)
+	require.Equal(t, wantResp, *resp)
+}
+
 func mockServer(t *testing.T, urlPath string, wantURLValues url.Values, wantCode int, wantResp any) *httptest.Server {
 	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == urlPath {
@@ -193,3 +348,13 @@ func openWeatherURLValues(wantAPIKey string) url.Values {
 	values.Set("q", wantCity)
 	return values
 }
+
+func openWeatherOneCallURLValues(wantAPIKey string, exclude string) url.Values {
+	values := url.Values{}
+	values.Set("appid", wantAPIKey)
+	values.Set("units", "metric")
+	values.Set("lat", "-33.8688")
+	values.Set("lon", "151.2093")
+	values.Set("exclude", exclude)
+	return values
+}
@@ -0,0 +1,141 @@
+package weather
+
+// Condition is a provider agnostic classification of the current weather.
+type Condition string
+
+const (
+	ConditionUnknown      Condition = "unknown"
+	ConditionClear        Condition = "clear"
+	ConditionPartlyCloudy Condition = "partly_cloudy"
+	ConditionCloudy       Condition = "cloudy"
+	ConditionFog          Condition = "fog"
+	ConditionHaze         Condition = "haze"
+	ConditionDust         Condition = "dust"
+	ConditionDrizzle      Condition = "drizzle"
+	ConditionRain         Condition = "rain"
+	ConditionSleet        Condition = "sleet"
+	ConditionSnow         Condition = "snow"
+	ConditionThunderstorm Condition = "thunderstorm"
+	ConditionSquall       Condition = "squall"
+	ConditionTornado      Condition = "tornado"
+)
+
+// Icon returns the identifier of the icon that represents the condition.
+// Conditions where the sky is visible have separate day and night icons.
+func (c Condition) Icon(isDay bool) string {
+	switch c {
+	case ConditionClear, ConditionPartlyCloudy:
+		if isDay {
+			return string(c) + "_day"
+		}
+		return string(c) + "_night"
+	default:
+		return string(c)
+	}
+}
+
+// weatherStackConditions maps weatherstack weather codes to a condition.
+// See https://weatherstack.com/site_resources/weatherstack-weather-condition-codes.zip
+var weatherStackConditions = map[int]Condition{
+	113: ConditionClear,        // Sunny / Clear
+	116: ConditionPartlyCloudy, // Partly cloudy
+	119: ConditionCloudy,       // Cloudy
+	122: ConditionCloudy,       // Overcast
+	143: ConditionFog,          // Mist
+	176: ConditionRain,         // Patchy rain possible
+	179: ConditionSnow,         // Patchy snow possible
+	182: ConditionSleet,        // Patchy sleet possible
+	185: ConditionSleet,        // Patchy freezing drizzle possible
+	200: ConditionThunderstorm, // Thundery outbreaks possible
+	227: ConditionSnow,         // Blowing snow
+	230: ConditionSnow,         // Blizzard
+	248: ConditionFog,          // Fog
+	260: ConditionFog,          // Freezing fog
+	263: ConditionDrizzle,      // Patchy light drizzle
+	266: ConditionDrizzle,      // Light drizzle
+	281: ConditionSleet,        // Freezing drizzle
+	284: ConditionSleet,        // Heavy freezing drizzle
+	293: ConditionRain,         // Patchy light rain
+	296: ConditionRain,         // Light rain
+	299: ConditionRain,         // Moderate rain at times
+	302: ConditionRain,         // Moderate rain
+	305: ConditionRain,         // Heavy rain at times
+	308: ConditionRain,         // Heavy rain
+	311: ConditionSleet,        // Light freezing rain
+	314: ConditionSleet,        // Moderate or heavy freezing rain
+	317: ConditionSleet,        // Light sleet
+	320: ConditionSleet,        // Moderate or heavy sleet
+	323: ConditionSnow,         // Patchy light snow
+	326: ConditionSnow,         // Light snow
+	329: ConditionSnow,         // Patchy moderate snow
+	332: ConditionSnow,         // Moderate snow
+	335: ConditionSnow,         // Patchy heavy snow
+	338: ConditionSnow,         // Heavy snow
+	350: ConditionSleet,        // Ice pellets
+	353: ConditionRain,         // Light rain shower
+	356: ConditionRain,         // Moderate or heavy rain shower
+	359: ConditionRain,         // Torrential rain shower
+	362: ConditionSleet,        // Light sleet showers
+	365: ConditionSleet,        // Moderate or heavy sleet showers
+	368: ConditionSnow,         // Light snow showers
+	371: ConditionSnow,         // Moderate or heavy snow showers
+	374: ConditionSleet,        // Light showers of ice pellets
+	377: ConditionSleet,        // Moderate or heavy showers of ice pellets
+	386: ConditionThunderstorm, // Patchy light rain with thunder
+	389: ConditionThunderstorm, // Moderate or heavy rain with thunder
+	392: ConditionThunderstorm, // Patchy light snow with thunder
+	395: ConditionThunderstorm, // Moderate or heavy snow with thunder
+}
+
+// openWeatherConditions maps OpenWeather condition ids that do not follow the
+// group rules in openWeatherCondition.
+// See https://openweathermap.org/weather-conditions
+var openWeatherConditions = map[int]Condition{
+	511: ConditionSleet,        // Freezing rain
+	701: ConditionFog,          // Mist
+	711: ConditionHaze,         // Smoke
+	721: ConditionHaze,         // Haze
+	731: ConditionDust,         // Sand/dust whirls
+	741: ConditionFog,          // Fog
+	751: ConditionDust,         // Sand
+	761: ConditionDust,         // Dust
+	762: ConditionDust,         // Volcanic ash
+	771: ConditionSquall,       // Squalls
+	781: ConditionTornado,      // Tornado
+	800: ConditionClear,        // Clear sky
+	801: ConditionPartlyCloudy, // Few clouds: 11-25%
+	802: ConditionPartlyCloudy, // Scattered clouds: 25-50%
+	803: ConditionCloudy,       // Broken clouds: 51-84%
+	804: ConditionCloudy,       // Overcast clouds: 85-100%
+}
+
+// weatherStackCondition returns the condition for a weatherstack weather code.
+func weatherStackCondition(code int) Condition {
+	if c, ok := weatherStackConditions[code]; ok {
+		return c
+	}
+	return ConditionUnknown
+}
+
+// openWeatherCondition returns the condition for an OpenWeather condition id.
+// Ids are grouped by their leading digit, e.g. 2xx are all thunderstorms.
+func openWeatherCondition(id int) Condition {
+	if c, ok := openWeatherConditions[id]; ok {
+		return c
+	}
+
+	switch {
+	case id >= 200 && id < 300:
+		return ConditionThunderstorm
+	case id >= 300 && id < 400:
+		return ConditionDrizzle
+	case id >= 500 && id < 600:
+		return ConditionRain
+	case id >= 611 && id < 620:
+		return ConditionSleet
+	case id >= 600 && id < 700:
+		return ConditionSnow
+	default:
+		return ConditionUnknown
+	}
+}
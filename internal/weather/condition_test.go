@@ -0,0 +1,75 @@
+package weather
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWeatherStackCondition(t *testing.T) {
+	tests := []struct {
+		code int
+		want Condition
+	}{
+		{code: 113, want: ConditionClear},
+		{code: 116, want: ConditionPartlyCloudy},
+		{code: 122, want: ConditionCloudy},
+		{code: 248, want: ConditionFog},
+		{code: 266, want: ConditionDrizzle},
+		{code: 308, want: ConditionRain},
+		{code: 320, want: ConditionSleet},
+		{code: 338, want: ConditionSnow},
+		{code: 389, want: ConditionThunderstorm},
+		{code: 999, want: ConditionUnknown},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.want, weatherStackCondition(tt.code), "code %d", tt.code)
+	}
+}
+
+func TestOpenWeatherCondition(t *testing.T) {
+	tests := []struct {
+		id   int
+		want Condition
+	}{
+		{id: 211, want: ConditionThunderstorm},
+		{id: 301, want: ConditionDrizzle},
+		{id: 502, want: ConditionRain},
+		{id: 511, want: ConditionSleet},
+		{id: 601, want: ConditionSnow},
+		{id: 613, want: ConditionSleet},
+		{id: 741, want: ConditionFog},
+		{id: 721, want: ConditionHaze},
+		{id: 781, want: ConditionTornado},
+		{id: 800, want: ConditionClear},
+		{id: 802, want: ConditionPartlyCloudy},
+		{id: 804, want: ConditionCloudy},
+		{id: 999, want: ConditionUnknown},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.want, openWeatherCondition(tt.id), "id %d", tt.id)
+	}
+}
+
+func TestCondition_Icon(t *testing.T) {
+	require.Equal(t, "clear_day", ConditionClear.Icon(true))
+	require.Equal(t, "clear_night", ConditionClear.Icon(false))
+	require.Equal(t, "partly_cloudy_night", ConditionPartlyCloudy.Icon(false))
+	require.Equal(t, "rain", ConditionRain.Icon(true))
+	require.Equal(t, "rain", ConditionRain.Icon(false))
+}
+
+func TestObservation_Icon_sameAcrossProviders(t *testing.T) {
+	ws := WeatherStackResponse{
+		Current: WeatherStackCurrent{WeatherCode: ptr(113), IsDay: ptr("no")},
+	}
+	ow := OpenWeatherResponse{
+		Weather: []OpenWeatherCondition{{ID: 800, Icon: "01n"}},
+	}
+
+	wsObs, owObs := ws.Observation(), ow.Observation()
+	require.Equal(t, "clear_night", wsObs.Icon())
+	require.Equal(t, wsObs.Icon(), owObs.Icon())
+}
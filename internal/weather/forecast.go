@@ -0,0 +1,251 @@
+package weather
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	// ForecastHours is the number of hourly periods included in a forecast.
+	ForecastHours = 48
+	// ForecastDays is the number of daily periods included in a forecast.
+	ForecastDays = 7
+)
+
+// Forecast is an hourly and daily forecast normalised across providers. Units
+// match those of Observation.
+type Forecast struct {
+	Hourly []HourlyForecast
+	Daily  []DailyForecast
+}
+
+// HourlyForecast is the expected conditions for the hour starting at Time.
+type HourlyForecast struct {
+	Time time.Time
+	Observation
+	PrecipChance *int // Percent
+}
+
+// DailyForecast is the expected conditions for the local day starting at Date.
+type DailyForecast struct {
+	Date         time.Time
+	MinTemp      float64
+	MaxTemp      float64
+	Condition    Condition
+	Description  string
+	Humidity     *int     // Percent
+	PrecipChance *int     // Percent
+	Precip       *float64 // Millimetres
+	Sunrise      *time.Time
+	Sunset       *time.Time
+}
+
+// Forecast normalises the weatherstack forecast response. weatherstack reports
+// local times without an offset, so they are interpreted in zone. Periods
+// before from are excluded.
+func (r *WeatherStackForecastResponse) Forecast(zone *time.Location, from time.Time) (Forecast, error) {
+	dates := make([]string, 0, len(r.Days))
+	for date := range r.Days {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	var forecast Forecast
+	for _, key := range dates {
+		day := r.Days[key]
+		date, err := time.ParseInLocation("2006-01-02", day.Date, zone)
+		if err != nil {
+			return Forecast{}, fmt.Errorf("invalid forecast date: %w", err)
+		}
+
+		daily := DailyForecast{
+			Date:    date,
+			MinTemp: day.MinTemp,
+			MaxTemp: day.MaxTemp,
+		}
+
+		if day.Astro != nil {
+			daily.Sunrise = parseWeatherStackClock(date, day.Astro.Sunrise)
+			daily.Sunset = parseWeatherStackClock(date, day.Astro.Sunset)
+		}
+
+		for _, h := range day.Hourly {
+			hourly, err := weatherStackHourly(date, h)
+			if err != nil {
+				return Forecast{}, err
+			}
+			forecast.Hourly = append(forecast.Hourly, hourly)
+			summariseHour(&daily, hourly, h.Precip)
+		}
+
+		forecast.Daily = append(forecast.Daily, daily)
+	}
+
+	return trimForecast(forecast, from), nil
+}
+
+// Forecast normalises the OpenWeather One Call response. Periods before from
+// are excluded.
+func (r *OpenWeatherOneCallResponse) Forecast(zone *time.Location, from time.Time) Forecast {
+	var forecast Forecast
+
+	for _, h := range r.Hourly {
+		forecast.Hourly = append(forecast.Hourly, HourlyForecast{
+			Time:         time.Unix(h.Dt, 0).In(zone),
+			Observation:  h.observation(),
+			PrecipChance: probabilityToPercent(h.Pop),
+		})
+	}
+
+	for _, d := range r.Daily {
+		daily := DailyForecast{
+			Date:         startOfDay(time.Unix(d.Dt, 0).In(zone)),
+			MinTemp:      d.Temp.Min,
+			MaxTemp:      d.Temp.Max,
+			Humidity:     d.Humidity,
+			PrecipChance: probabilityToPercent(d.Pop),
+			Sunrise:      unixTime(d.Sunrise, zone),
+			Sunset:       unixTime(d.Sunset, zone),
+		}
+		daily.Condition, daily.Description, _ = openWeatherSummary(d.Weather)
+
+		if d.Rain != nil || d.Snow != nil {
+			var precip float64
+			for _, v := range []*float64{d.Rain, d.Snow} {
+				if v != nil {
+					precip += *v
+				}
+			}
+			daily.Precip = &precip
+		}
+
+		forecast.Daily = append(forecast.Daily, daily)
+	}
+
+	return trimForecast(forecast, from)
+}
+
+func (h *OpenWeatherHourly) observation() Observation {
+	obs := Observation{
+		Temperature: h.Temp,
+		FeelsLike:   h.FeelsLike,
+		Humidity:    h.Humidity,
+		Pressure:    h.Pressure,
+		CloudCover:  h.Clouds,
+		Visibility:  metresToKm(h.Visibility),
+		WindSpeed:   ToKmh(h.WindSpeed, MetresPerSecond),
+		WindDegree:  h.WindDeg,
+		WindGust:    msToKmh(h.WindGust),
+	}
+	obs.Condition, obs.Description, obs.IsDay = openWeatherSummary(h.Weather)
+	return obs
+}
+
+func weatherStackHourly(date time.Time, h WeatherStackHourly) (HourlyForecast, error) {
+	hhmm, err := strconv.Atoi(h.Time)
+	if err != nil {
+		return HourlyForecast{}, fmt.Errorf("invalid forecast hour: %w", err)
+	}
+
+	obs := Observation{
+		Temperature: float64(h.Temperature),
+		FeelsLike:   h.FeelsLike,
+		Humidity:    h.Humidity,
+		Pressure:    h.Pressure,
+		CloudCover:  h.CloudCover,
+		Visibility:  h.Visibility,
+		WindSpeed:   float64(h.WindSpeed),
+		WindDegree:  h.WindDegree,
+		WindGust:    h.WindGust,
+	}
+	obs.Condition, obs.Description = weatherStackSummary(h.WeatherCode, h.WeatherDescriptions)
+
+	return HourlyForecast{
+		Time:         time.Date(date.Year(), date.Month(), date.Day(), hhmm/100, hhmm%100, 0, 0, date.Location()),
+		Observation:  obs,
+		PrecipChance: h.ChanceOfRain,
+	}, nil
+}
+
+// summariseHour folds an hourly period into the daily summary. weatherstack
+// does not report a daily condition, so the midday condition is used.
+func summariseHour(daily *DailyForecast, hourly HourlyForecast, precip *float64) {
+	if hourly.Time.Hour() == 12 || daily.Condition == "" {
+		daily.Condition = hourly.Condition
+		daily.Description = hourly.Description
+	}
+
+	if hourly.PrecipChance != nil && (daily.PrecipChance == nil || *hourly.PrecipChance > *daily.PrecipChance) {
+		chance := *hourly.PrecipChance
+		daily.PrecipChance = &chance
+	}
+
+	if precip != nil {
+		if daily.Precip == nil {
+			daily.Precip = new(float64)
+		}
+		*daily.Precip += *precip
+	}
+}
+
+// trimForecast drops periods that have passed and limits the forecast to
+// ForecastHours and ForecastDays.
+func trimForecast(forecast Forecast, from time.Time) Forecast {
+	var trimmed Forecast
+
+	hour := from.Truncate(time.Hour)
+	for _, h := range forecast.Hourly {
+		if len(trimmed.Hourly) == ForecastHours {
+			break
+		}
+		if !h.Time.Before(hour) {
+			trimmed.Hourly = append(trimmed.Hourly, h)
+		}
+	}
+
+	for _, d := range forecast.Daily {
+		if len(trimmed.Daily) == ForecastDays {
+			break
+		}
+		if !d.Date.Before(startOfDay(from.In(d.Date.Location()))) {
+			trimmed.Daily = append(trimmed.Daily, d)
+		}
+	}
+
+	return trimmed
+}
+
+// parseWeatherStackClock parses a local clock time such as '06:12 AM' on date.
+// Nil is returned for values weatherstack uses when there is no event, such as
+// 'No sunrise'.
+func parseWeatherStackClock(date time.Time, clock string) *time.Time {
+	t, err := time.Parse("03:04 PM", clock)
+	if err != nil {
+		return nil
+	}
+	local := time.Date(date.Year(), date.Month(), date.Day(), t.Hour(), t.Minute(), 0, 0, date.Location())
+	return &local
+}
+
+func probabilityToPercent(p *float64) *int {
+	if p == nil {
+		return nil
+	}
+	percent := int(math.Round(*p * 100))
+	return &percent
+}
+
+func unixTime(sec *int64, zone *time.Location) *time.Time {
+	if sec == nil {
+		return nil
+	}
+	t := time.Unix(*sec, 0).In(zone)
+	return &t
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,107 @@
+package weather
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWeatherStackForecastResponse_Forecast(t *testing.T) {
+	zone := time.FixedZone("AEST", 10*60*60)
+	resp := WeatherStackForecastResponse{
+		Days: map[string]WeatherStackForecastDay{
+			"2022-05-02": {
+				Date:    "2022-05-02",
+				MinTemp: 11,
+				MaxTemp: 21,
+				Hourly: []WeatherStackHourly{
+					{Time: "1200", Temperature: 21, WindSpeed: 10, WeatherCode: ptr(113), ChanceOfRain: ptr(0)},
+				},
+			},
+			"2022-05-01": {
+				Date:    "2022-05-01",
+				Astro:   &WeatherStackAstro{Sunrise: "06:34 AM", Sunset: "05:11 PM"},
+				MinTemp: 10,
+				MaxTemp: 20,
+				Hourly: []WeatherStackHourly{
+					{Time: "900", Temperature: 12, WindSpeed: 5, WeatherCode: ptr(116), ChanceOfRain: ptr(10), Precip: ptr(0.5)},
+					{Time: "1200", Temperature: 20, WindSpeed: 8, WeatherCode: ptr(296), ChanceOfRain: ptr(80), Precip: ptr(1.5)},
+				},
+			},
+		},
+	}
+
+	from := time.Date(2022, 5, 1, 8, 30, 0, 0, zone)
+	forecast, err := resp.Forecast(zone, from)
+	require.NoError(t, err)
+
+	require.Len(t, forecast.Hourly, 3)
+	require.Equal(t, time.Date(2022, 5, 1, 9, 0, 0, 0, zone), forecast.Hourly[0].Time)
+	require.Equal(t, ConditionPartlyCloudy, forecast.Hourly[0].Condition)
+	require.Equal(t, time.Date(2022, 5, 2, 12, 0, 0, 0, zone), forecast.Hourly[2].Time)
+
+	require.Len(t, forecast.Daily, 2)
+	day := forecast.Daily[0]
+	require.Equal(t, time.Date(2022, 5, 1, 0, 0, 0, 0, zone), day.Date)
+	require.Equal(t, 10.0, day.MinTemp)
+	require.Equal(t, 20.0, day.MaxTemp)
+	require.Equal(t, ConditionRain, day.Condition) // Midday condition
+	require.Equal(t, ptr(80), day.PrecipChance)
+	require.Equal(t, ptr(2.0), day.Precip)
+	require.Equal(t, ptr(time.Date(2022, 5, 1, 6, 34, 0, 0, zone)), day.Sunrise)
+	require.Equal(t, ptr(time.Date(2022, 5, 1, 17, 11, 0, 0, zone)), day.Sunset)
+}
+
+func TestWeatherStackForecastResponse_Forecast_invalidDate(t *testing.T) {
+	resp := WeatherStackForecastResponse{
+		Days: map[string]WeatherStackForecastDay{
+			"invalid": {Date: "invalid"},
+		},
+	}
+	_, err := resp.Forecast(time.UTC, time.Now())
+	require.Error(t, err)
+}
+
+func TestOpenWeatherOneCallResponse_Forecast(t *testing.T) {
+	zone := time.FixedZone("AEST", 10*60*60)
+	from := time.Date(2022, 5, 1, 8, 30, 0, 0, zone)
+
+	resp := OpenWeatherOneCallResponse{
+		Daily: []OpenWeatherDaily{
+			{
+				Dt:      time.Date(2022, 5, 1, 12, 0, 0, 0, zone).Unix(),
+				Sunrise: ptr(time.Date(2022, 5, 1, 6, 34, 0, 0, zone).Unix()),
+				Temp:    OpenWeatherDailyTemp{Min: 10, Max: 20},
+				Weather: []OpenWeatherCondition{{ID: 500, Description: "light rain", Icon: "10d"}},
+				Pop:     ptr(0.8),
+				Rain:    ptr(2.0),
+			},
+		},
+	}
+	for i := 0; i < 60; i++ {
+		resp.Hourly = append(resp.Hourly, OpenWeatherHourly{
+			Dt:        from.Truncate(time.Hour).Add(time.Duration(i-1) * time.Hour).Unix(),
+			Temp:      15,
+			WindSpeed: 10,
+			WindGust:  ptr(20.0),
+		})
+	}
+
+	forecast := resp.Forecast(zone, from)
+
+	// The hour that has passed is excluded and the remainder is limited
+	require.Len(t, forecast.Hourly, ForecastHours)
+	require.True(t, forecast.Hourly[0].Time.Equal(from.Truncate(time.Hour)))
+	require.Equal(t, 36.0, forecast.Hourly[0].WindSpeed)
+	require.Equal(t, ptr(72.0), forecast.Hourly[0].WindGust)
+
+	require.Len(t, forecast.Daily, 1)
+	day := forecast.Daily[0]
+	require.True(t, day.Date.Equal(time.Date(2022, 5, 1, 0, 0, 0, 0, zone)))
+	require.Equal(t, ConditionRain, day.Condition)
+	require.Equal(t, ptr(80), day.PrecipChance)
+	require.Equal(t, ptr(2.0), day.Precip)
+	require.True(t, day.Sunrise.Equal(time.Date(2022, 5, 1, 6, 34, 0, 0, zone)))
+	require.Nil(t, day.Sunset)
+}
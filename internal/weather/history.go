@@ -0,0 +1,51 @@
+package weather
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrHistoryNotFound is returned when a provider response does not contain an
+// observation for the requested time.
+var ErrHistoryNotFound = errors.New("historical observation not found")
+
+// Observation returns the historical observation for the hour starting at t.
+// weatherstack reports local times without an offset, so t must be in the
+// zone of the queried location.
+func (r *WeatherStackHistoryResponse) Observation(t time.Time) (Observation, error) {
+	day, ok := r.Days[t.Format("2006-01-02")]
+	if !ok {
+		return Observation{}, ErrHistoryNotFound
+	}
+
+	date, err := time.ParseInLocation("2006-01-02", day.Date, t.Location())
+	if err != nil {
+		return Observation{}, err
+	}
+
+	for _, h := range day.Hourly {
+		hourly, err := weatherStackHourly(date, h)
+		if err != nil {
+			return Observation{}, err
+		}
+		if hourly.Time.Equal(t.Truncate(time.Hour)) {
+			obs := hourly.Observation
+			obs.ObservedAt = &hourly.Time
+			return obs, nil
+		}
+	}
+
+	return Observation{}, ErrHistoryNotFound
+}
+
+// Observation returns the historical observation. The time machine API returns
+// a single observation for the requested time.
+func (r *OpenWeatherTimeMachineResponse) Observation() (Observation, error) {
+	if len(r.Data) == 0 {
+		return Observation{}, ErrHistoryNotFound
+	}
+	obs := r.Data[0].observation()
+	observedAt := time.Unix(r.Data[0].Dt, 0).UTC()
+	obs.ObservedAt = &observedAt
+	return obs, nil
+}
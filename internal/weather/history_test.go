@@ -0,0 +1,52 @@
+package weather
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWeatherStackHistoryResponse_Observation(t *testing.T) {
+	zone := time.FixedZone("AEST", 10*60*60)
+	resp := WeatherStackHistoryResponse{
+		Days: map[string]WeatherStackForecastDay{
+			"2022-05-01": {
+				Date: "2022-05-01",
+				Hourly: []WeatherStackHourly{
+					{Time: "900", Temperature: 12, WindSpeed: 5},
+					{Time: "1200", Temperature: 20, WindSpeed: 8, WeatherCode: ptr(113)},
+				},
+			},
+		},
+	}
+
+	obs, err := resp.Observation(time.Date(2022, 5, 1, 12, 0, 0, 0, zone))
+	require.NoError(t, err)
+	require.Equal(t, 20.0, obs.Temperature)
+	require.Equal(t, 8.0, obs.WindSpeed)
+	require.Equal(t, ConditionClear, obs.Condition)
+	require.Equal(t, ptr(time.Date(2022, 5, 1, 12, 0, 0, 0, zone)), obs.ObservedAt)
+
+	_, err = resp.Observation(time.Date(2022, 5, 1, 15, 0, 0, 0, zone))
+	require.ErrorIs(t, err, ErrHistoryNotFound)
+
+	_, err = resp.Observation(time.Date(2022, 5, 2, 12, 0, 0, 0, zone))
+	require.ErrorIs(t, err, ErrHistoryNotFound)
+}
+
+func TestOpenWeatherTimeMachineResponse_Observation(t *testing.T) {
+	resp := OpenWeatherTimeMachineResponse{
+		Data: []OpenWeatherHourly{{Dt: 1651370400, Temp: 20, WindSpeed: 10, Humidity: ptr(60)}},
+	}
+
+	obs, err := resp.Observation()
+	require.NoError(t, err)
+	require.Equal(t, 20.0, obs.Temperature)
+	require.Equal(t, 36.0, obs.WindSpeed)
+	require.Equal(t, ptr(60), obs.Humidity)
+	require.Equal(t, ptr(time.Unix(1651370400, 0).UTC()), obs.ObservedAt)
+
+	_, err = (&OpenWeatherTimeMachineResponse{}).Observation()
+	require.ErrorIs(t, err, ErrHistoryNotFound)
+}
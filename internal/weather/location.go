@@ -0,0 +1,12 @@
+package weather
+
+import "time"
+
+// Location is a named place. Some provider endpoints are queried by name and
+// others by coordinates. Zone is used to interpret provider local times.
+type Location struct {
+	Name string
+	Lat  float64
+	Lon  float64
+	Zone *time.Location
+}
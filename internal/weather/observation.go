@@ -0,0 +1,158 @@
+package weather
+
+import (
+	"strings"
+	"time"
+)
+
+// metresPerSecToKmh converts a speed in metres per second to kilometres per hour.
+const metresPerSecToKmh = 3.6
+
+// Observation is a snapshot of current weather conditions normalised across
+// providers. Temperatures are in degrees Celsius, speeds in kilometres per hour,
+// pressure in hectopascals and visibility in kilometres. Optional fields are nil
+// when the provider does not report them.
+type Observation struct {
+	Temperature       float64
+	FeelsLike         *float64
+	Humidity          *int // Percent
+	Pressure          *float64
+	CloudCover        *int // Percent
+	Visibility        *float64
+	WindSpeed         float64
+	ReportedWindSpeed float64 // In the provider's unit, as served by the v1 API
+	WindDegree        *int
+	WindGust          *float64
+	Condition         Condition
+	Description       string
+	IsDay             *bool
+	ObservedAt        *time.Time // When the provider measured the conditions
+}
+
+// Icon returns the identifier of the icon for the observed condition. The day
+// icon is used when it is unknown whether it is day or night.
+func (o *Observation) Icon() string {
+	return o.Condition.Icon(o.IsDay == nil || *o.IsDay)
+}
+
+// Observation normalises the weatherstack response. The client requests metric
+// units so no conversion is required. weatherstack does not report wind gusts.
+func (r *WeatherStackResponse) Observation() Observation {
+	obs := Observation{
+		Temperature:       float64(r.Current.Temperature),
+		FeelsLike:         r.Current.FeelsLike,
+		Humidity:          r.Current.Humidity,
+		Pressure:          r.Current.Pressure,
+		CloudCover:        r.Current.CloudCover,
+		Visibility:        r.Current.Visibility,
+		WindSpeed:         float64(r.Current.WindSpeed),
+		ReportedWindSpeed: float64(r.Current.WindSpeed),
+		WindDegree:        r.Current.WindDegree,
+	}
+
+	obs.Condition, obs.Description = weatherStackSummary(r.Current.WeatherCode, r.Current.WeatherDescriptions)
+
+	if r.Current.IsDay != nil {
+		isDay := *r.Current.IsDay == "yes"
+		obs.IsDay = &isDay
+	}
+
+	if r.Current.ObservationTime != nil && r.Location.LocaltimeEpoch != nil {
+		obs.ObservedAt = weatherStackObservedAt(*r.Current.ObservationTime, time.Unix(*r.Location.LocaltimeEpoch, 0))
+	}
+
+	return obs
+}
+
+// weatherStackObservedAt returns the time of the most recent observation made at
+// the UTC clock time before the request time. weatherstack only reports the
+// time of day of an observation.
+func weatherStackObservedAt(clock string, requestedAt time.Time) *time.Time {
+	t, err := time.Parse("03:04 PM", clock)
+	if err != nil {
+		return nil
+	}
+
+	requestedAt = requestedAt.UTC()
+	observedAt := time.Date(requestedAt.Year(), requestedAt.Month(), requestedAt.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+	if observedAt.After(requestedAt) {
+		observedAt = observedAt.AddDate(0, 0, -1)
+	}
+
+	return &observedAt
+}
+
+// Observation normalises the OpenWeather response. OpenWeather reports metric
+// speeds in metres per second and visibility in metres.
+func (r *OpenWeatherResponse) Observation() Observation {
+	obs := Observation{
+		Temperature:       r.Main.Temp,
+		FeelsLike:         r.Main.FeelsLike,
+		Humidity:          r.Main.Humidity,
+		Pressure:          r.Main.Pressure,
+		CloudCover:        r.Clouds.All,
+		Visibility:        metresToKm(r.Visibility),
+		WindSpeed:         ToKmh(r.Wind.Speed, MetresPerSecond),
+		ReportedWindSpeed: r.Wind.Speed,
+		WindDegree:        r.Wind.Deg,
+		WindGust:          msToKmh(r.Wind.Gust),
+	}
+
+	obs.Condition, obs.Description, obs.IsDay = openWeatherSummary(r.Weather)
+
+	if r.Dt != nil {
+		observedAt := time.Unix(*r.Dt, 0).UTC()
+		obs.ObservedAt = &observedAt
+	}
+
+	return obs
+}
+
+// weatherStackSummary returns the condition and description for a weatherstack
+// weather code and its descriptions.
+func weatherStackSummary(code *int, descriptions []string) (Condition, string) {
+	condition := ConditionUnknown
+	if code != nil {
+		condition = weatherStackCondition(*code)
+	}
+
+	var description string
+	if len(descriptions) > 0 {
+		description = descriptions[0]
+	}
+
+	return condition, description
+}
+
+// openWeatherSummary returns the condition, description and whether it is day
+// for a set of OpenWeather conditions. The first condition is the primary one.
+func openWeatherSummary(conditions []OpenWeatherCondition) (Condition, string, *bool) {
+	if len(conditions) == 0 {
+		return ConditionUnknown, "", nil
+	}
+
+	primary := conditions[0]
+	var isDay *bool
+	if primary.Icon != "" {
+		day := strings.HasSuffix(primary.Icon, "d")
+		isDay = &day
+	}
+
+	return openWeatherCondition(primary.ID), primary.Description, isDay
+}
+
+func msToKmh(speed *float64) *float64 {
+	if speed == nil {
+		return nil
+	}
+	kmh := ToKmh(*speed, MetresPerSecond)
+	return &kmh
+}
+
+func metresToKm(distance *float64) *float64 {
+	if distance == nil {
+		return nil
+	}
+	km := *distance / 1000
+	return &km
+}
@@ -0,0 +1,124 @@
+package weather
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWeatherStackResponse_Observation(t *testing.T) {
+	resp := WeatherStackResponse{
+		Current: WeatherStackCurrent{
+			WindSpeed:   10,
+			Temperature: 20,
+			WindDegree:  ptr(90),
+			Pressure:    ptr(1012.0),
+			Humidity:    ptr(60),
+			CloudCover:  ptr(25),
+			FeelsLike:   ptr(19.0),
+			Visibility:  ptr(10.0),
+
+			WeatherCode:         ptr(296),
+			WeatherDescriptions: []string{"Light rain"},
+			IsDay:               ptr("no"),
+
+			ObservationTime: ptr("12:14 PM"),
+		},
+		Location: WeatherStackLocation{
+			LocaltimeEpoch: ptr(time.Date(2022, 5, 1, 12, 20, 0, 0, time.UTC).Unix()),
+		},
+	}
+
+	want := Observation{
+		Temperature:       20,
+		FeelsLike:         ptr(19.0),
+		Humidity:          ptr(60),
+		Pressure:          ptr(1012.0),
+		CloudCover:        ptr(25),
+		Visibility:        ptr(10.0),
+		WindSpeed:         10,
+		ReportedWindSpeed: 10,
+		WindDegree:        ptr(90),
+		Condition:         ConditionRain,
+		Description:       "Light rain",
+		IsDay:             ptr(false),
+		ObservedAt:        ptr(time.Date(2022, 5, 1, 12, 14, 0, 0, time.UTC)),
+	}
+	require.Equal(t, want, resp.Observation())
+}
+
+func TestOpenWeatherResponse_Observation(t *testing.T) {
+	resp := OpenWeatherResponse{
+		Main: OpenWeatherMain{
+			Temp:      20,
+			FeelsLike: ptr(19.0),
+			Pressure:  ptr(1012.0),
+			Humidity:  ptr(60),
+		},
+		Wind: OpenWeatherWind{
+			Speed: 10,
+			Deg:   ptr(90),
+			Gust:  ptr(15.0),
+		},
+		Clouds:     OpenWeatherClouds{All: ptr(25)},
+		Visibility: ptr(10000.0),
+		Weather: []OpenWeatherCondition{
+			{ID: 800, Main: "Clear", Description: "clear sky", Icon: "01d"},
+		},
+		Dt: ptr(time.Date(2022, 5, 1, 12, 14, 0, 0, time.UTC).Unix()),
+	}
+
+	want := Observation{
+		Temperature:       20,
+		FeelsLike:         ptr(19.0),
+		Humidity:          ptr(60),
+		Pressure:          ptr(1012.0),
+		CloudCover:        ptr(25),
+		Visibility:        ptr(10.0),
+		WindSpeed:         36,
+		ReportedWindSpeed: 10, // Metres per second
+		WindDegree:        ptr(90),
+		WindGust:          ptr(54.0),
+		Condition:         ConditionClear,
+		Description:       "clear sky",
+		IsDay:             ptr(true),
+		ObservedAt:        ptr(time.Date(2022, 5, 1, 12, 14, 0, 0, time.UTC)),
+	}
+	require.Equal(t, want, resp.Observation())
+}
+
+func TestObservation_missingFields(t *testing.T) {
+	resp := OpenWeatherResponse{
+		Main: OpenWeatherMain{Temp: 20},
+		Wind: OpenWeatherWind{Speed: 10},
+	}
+
+	obs := resp.Observation()
+	require.Nil(t, obs.FeelsLike)
+	require.Nil(t, obs.Humidity)
+	require.Nil(t, obs.Pressure)
+	require.Nil(t, obs.CloudCover)
+	require.Nil(t, obs.Visibility)
+	require.Nil(t, obs.WindDegree)
+	require.Nil(t, obs.WindGust)
+	require.Nil(t, obs.IsDay)
+	require.Nil(t, obs.ObservedAt)
+	require.Equal(t, ConditionUnknown, obs.Condition)
+}
+
+func TestWeatherStackObservedAt(t *testing.T) {
+	requestedAt := time.Date(2022, 5, 1, 0, 5, 0, 0, time.UTC)
+
+	// Observed on the same UTC day as the request
+	require.Equal(t, ptr(time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)), weatherStackObservedAt("12:00 AM", requestedAt))
+
+	// Observed on the previous UTC day
+	require.Equal(t, ptr(time.Date(2022, 4, 30, 23, 45, 0, 0, time.UTC)), weatherStackObservedAt("11:45 PM", requestedAt))
+
+	require.Nil(t, weatherStackObservedAt("invalid", requestedAt))
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,59 @@
+package weather
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+)
+
+func TestTracingTransport(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     int
+		wantCode codes.Code
+	}{
+		{name: "success", code: http.StatusOK, wantCode: codes.Unset},
+		{name: "error status", code: http.StatusUnauthorized, wantCode: codes.Error},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			prev := otel.GetTracerProvider()
+			otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+			defer otel.SetTracerProvider(prev)
+
+			var gotTraceparent string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotTraceparent = r.Header.Get("traceparent")
+				w.WriteHeader(tt.code)
+			}))
+			defer srv.Close()
+
+			_, err := newRestyClient("some-provider", srv.URL).R().
+				SetContext(context.Background()).
+				SetQueryParam("access_key", wantAPIKey).
+				Get("/current")
+			require.NoError(t, err)
+			require.Empty(t, gotTraceparent)
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 1)
+			require.Equal(t, "some-provider GET /current", spans[0].Name())
+			require.Equal(t, tt.wantCode, spans[0].Status().Code)
+			require.Contains(t, spans[0].Attributes(), semconv.HTTPURLKey.String(srv.URL+"/current"))
+			require.Contains(t, spans[0].Attributes(), semconv.HTTPStatusCodeKey.Int(tt.code))
+			for _, attr := range spans[0].Attributes() {
+				require.NotContains(t, attr.Value.Emit(), wantAPIKey)
+			}
+		})
+	}
+}
@@ -1,12 +1,29 @@
 package weather
 
 type WeatherStackCurrent struct {
-	WindSpeed   int `json:"wind_speed"`
-	Temperature int `json:"temperature"`
+	WindSpeed   int      `json:"wind_speed"`
+	Temperature int      `json:"temperature"`
+	WindDegree  *int     `json:"wind_degree,omitempty"`
+	Pressure    *float64 `json:"pressure,omitempty"`
+	Humidity    *int     `json:"humidity,omitempty"`
+	CloudCover  *int     `json:"cloudcover,omitempty"`
+	FeelsLike   *float64 `json:"feelslike,omitempty"`
+	Visibility  *float64 `json:"visibility,omitempty"`
+
+	WeatherCode         *int     `json:"weather_code,omitempty"`
+	WeatherDescriptions []string `json:"weather_descriptions,omitempty"`
+	IsDay               *string  `json:"is_day,omitempty"` // 'yes' or 'no'
+
+	ObservationTime *string `json:"observation_time,omitempty"` // UTC time of day e.g. '12:14 PM'
+}
+
+type WeatherStackLocation struct {
+	LocaltimeEpoch *int64 `json:"localtime_epoch,omitempty"` // Unix seconds at the time of the request
 }
 
 type WeatherStackResponse struct {
-	Current WeatherStackCurrent `json:"current"`
+	Location WeatherStackLocation `json:"location"`
+	Current  WeatherStackCurrent  `json:"current"`
 }
 
 type WeatherStackError struct {
@@ -21,19 +38,149 @@ type WeatherStackErrorResponse struct {
 }
 
 type OpenWeatherMain struct {
-	Temp float64 `json:"temp"`
+	Temp      float64  `json:"temp"`
+	FeelsLike *float64 `json:"feels_like,omitempty"`
+	Pressure  *float64 `json:"pressure,omitempty"`
+	Humidity  *int     `json:"humidity,omitempty"`
 }
 
 type OpenWeatherWind struct {
-	Speed float64 `json:"speed"`
+	Speed float64  `json:"speed"`
+	Deg   *int     `json:"deg,omitempty"`
+	Gust  *float64 `json:"gust,omitempty"`
+}
+
+type OpenWeatherClouds struct {
+	All *int `json:"all,omitempty"`
+}
+
+type OpenWeatherCondition struct {
+	ID          int    `json:"id"`
+	Main        string `json:"main"`
+	Description string `json:"description"`
+	Icon        string `json:"icon"` // Suffixed with 'd' for day or 'n' for night
 }
 
 type OpenWeatherResponse struct {
-	Main OpenWeatherMain
-	Wind OpenWeatherWind
+	Main       OpenWeatherMain
+	Wind       OpenWeatherWind
+	Clouds     OpenWeatherClouds      `json:"clouds"`
+	Visibility *float64               `json:"visibility,omitempty"` // Metres
+	Weather    []OpenWeatherCondition `json:"weather,omitempty"`
+	Dt         *int64                 `json:"dt,omitempty"` // Unix seconds of the observation
 }
 
 type OpenWeatherErrorResponse struct {
 	Code    int    `json:"cod"` // 'code' misspelled in open weather response
 	Message string `json:"message"`
 }
+
+type WeatherStackAstro struct {
+	Sunrise string `json:"sunrise"` // Local time e.g. '06:12 AM'
+	Sunset  string `json:"sunset"`
+}
+
+type WeatherStackHourly struct {
+	Time                string   `json:"time"` // Local time in 'hmm' format e.g. '0', '100', '2300'
+	Temperature         int      `json:"temperature"`
+	WindSpeed           int      `json:"wind_speed"`
+	WindDegree          *int     `json:"wind_degree,omitempty"`
+	WindGust            *float64 `json:"windgust,omitempty"`
+	WeatherCode         *int     `json:"weather_code,omitempty"`
+	WeatherDescriptions []string `json:"weather_descriptions,omitempty"`
+	Precip              *float64 `json:"precip,omitempty"`
+	Humidity            *int     `json:"humidity,omitempty"`
+	Visibility          *float64 `json:"visibility,omitempty"`
+	Pressure            *float64 `json:"pressure,omitempty"`
+	CloudCover          *int     `json:"cloudcover,omitempty"`
+	FeelsLike           *float64 `json:"feelslike,omitempty"`
+	ChanceOfRain        *int     `json:"chanceofrain,omitempty"`
+}
+
+type WeatherStackForecastDay struct {
+	Date    string               `json:"date"` // Local date e.g. '2022-05-01'
+	Astro   *WeatherStackAstro   `json:"astro,omitempty"`
+	MinTemp float64              `json:"mintemp"`
+	MaxTemp float64              `json:"maxtemp"`
+	Hourly  []WeatherStackHourly `json:"hourly"`
+}
+
+type WeatherStackForecastResponse struct {
+	Days map[string]WeatherStackForecastDay `json:"forecast"` // Keyed by local date
+}
+
+type OpenWeatherHourly struct {
+	Dt         int64                  `json:"dt"` // Unix seconds
+	Temp       float64                `json:"temp"`
+	FeelsLike  *float64               `json:"feels_like,omitempty"`
+	Pressure   *float64               `json:"pressure,omitempty"`
+	Humidity   *int                   `json:"humidity,omitempty"`
+	Clouds     *int                   `json:"clouds,omitempty"`
+	Visibility *float64               `json:"visibility,omitempty"` // Metres
+	WindSpeed  float64                `json:"wind_speed"`
+	WindDeg    *int                   `json:"wind_deg,omitempty"`
+	WindGust   *float64               `json:"wind_gust,omitempty"`
+	Weather    []OpenWeatherCondition `json:"weather,omitempty"`
+	Pop        *float64               `json:"pop,omitempty"` // Probability of precipitation between 0 and 1
+}
+
+type OpenWeatherDailyTemp struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+type OpenWeatherDaily struct {
+	Dt       int64                  `json:"dt"` // Unix seconds
+	Sunrise  *int64                 `json:"sunrise,omitempty"`
+	Sunset   *int64                 `json:"sunset,omitempty"`
+	Temp     OpenWeatherDailyTemp   `json:"temp"`
+	Humidity *int                   `json:"humidity,omitempty"`
+	Weather  []OpenWeatherCondition `json:"weather,omitempty"`
+	Pop      *float64               `json:"pop,omitempty"`
+	Rain     *float64               `json:"rain,omitempty"` // Millimetres
+	Snow     *float64               `json:"snow,omitempty"` // Millimetres
+}
+
+type OpenWeatherAlert struct {
+	SenderName  string   `json:"sender_name"`
+	Event       string   `json:"event"`
+	Start       int64    `json:"start"` // Unix seconds
+	End         int64    `json:"end"`   // Unix seconds
+	Description string   `json:"description"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+type OpenWeatherOneCallResponse struct {
+	Hourly   []OpenWeatherHourly `json:"hourly,omitempty"`
+	Daily    []OpenWeatherDaily  `json:"daily,omitempty"`
+	Warnings []OpenWeatherAlert  `json:"alerts,omitempty"`
+}
+
+type WeatherStackHistoryResponse struct {
+	Days map[string]WeatherStackForecastDay `json:"historical"` // Keyed by local date
+}
+
+type OpenWeatherTimeMachineResponse struct {
+	Data []OpenWeatherHourly `json:"data"`
+}
+
+type OpenWeatherAirPollutionMain struct {
+	AQI int `json:"aqi"` // 1 (good) to 5 (very poor)
+}
+
+type OpenWeatherAirPollutionComponents struct {
+	PM25 *float64 `json:"pm2_5,omitempty"` // μg/m3
+	PM10 *float64 `json:"pm10,omitempty"`  // μg/m3
+	O3   *float64 `json:"o3,omitempty"`    // μg/m3
+	NO2  *float64 `json:"no2,omitempty"`   // μg/m3
+}
+
+type OpenWeatherAirPollution struct {
+	Dt         int64                             `json:"dt"` // Unix seconds
+	Main       OpenWeatherAirPollutionMain       `json:"main"`
+	Components OpenWeatherAirPollutionComponents `json:"components"`
+}
+
+type OpenWeatherAirPollutionResponse struct {
+	List []OpenWeatherAirPollution `json:"list"`
+}
@@ -0,0 +1,206 @@
+package weather
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrImplausibleObservation is returned when an observation is empty or has a
+// value outside of its plausible range, which usually indicates an upstream
+// glitch rather than real weather.
+var ErrImplausibleObservation = errors.New("implausible observation")
+
+// Range is an inclusive range of plausible values. A nil bound is unbounded.
+type Range struct {
+	Min *float64 `yaml:"min"`
+	Max *float64 `yaml:"max"`
+}
+
+// Limits are the plausible ranges of observation fields in the units of
+// Observation.
+type Limits struct {
+	Temperature Range `yaml:"temperature"`
+	FeelsLike   Range `yaml:"feelsLike"`
+	Humidity    Range `yaml:"humidity"`
+	Pressure    Range `yaml:"pressure"`
+	CloudCover  Range `yaml:"cloudCover"`
+	Visibility  Range `yaml:"visibility"`
+	WindSpeed   Range `yaml:"windSpeed"`
+	WindGust    Range `yaml:"windGust"`
+}
+
+// DefaultLimits are plausible anywhere on earth. They are based on recorded
+// extremes with some leeway.
+var DefaultLimits = Limits{
+	Temperature: newRange(-90, 60),
+	FeelsLike:   newRange(-110, 80),
+	Humidity:    newRange(0, 100),
+	Pressure:    newRange(850, 1090),
+	CloudCover:  newRange(0, 100),
+	Visibility:  newRange(0, 500),
+	WindSpeed:   newRange(0, 410),
+	WindGust:    newRange(0, 410),
+}
+
+// Validator checks that observations are plausible. Limits can be narrowed for
+// specific locations.
+type Validator struct {
+	defaults  Limits
+	locations map[string]Limits
+}
+
+// NewValidator creates a new validator. Bounds that are not set in the location
+// limits fall back to those in defaults, and bounds not set in defaults fall
+// back to DefaultLimits.
+func NewValidator(defaults Limits, locations map[string]Limits) *Validator {
+	v := &Validator{
+		defaults:  DefaultLimits.merge(defaults),
+		locations: make(map[string]Limits, len(locations)),
+	}
+	for name, limits := range locations {
+		v.locations[strings.ToLower(name)] = v.defaults.merge(limits)
+	}
+	return v
+}
+
+// Validate returns an error wrapping ErrImplausibleObservation that describes
+// every violation if the observation for the named location is implausible.
+func (v *Validator) Validate(location string, obs *Observation) error {
+	if obs.isEmpty() {
+		return fmt.Errorf("%w: no values reported", ErrImplausibleObservation)
+	}
+
+	limits, ok := v.locations[strings.ToLower(location)]
+	if !ok {
+		limits = v.defaults
+	}
+
+	var violations []string
+	check := func(field string, value *float64, r Range) {
+		if value == nil {
+			return
+		}
+		if (r.Min != nil && *value < *r.Min) || (r.Max != nil && *value > *r.Max) {
+			violations = append(violations, fmt.Sprintf("%s %g outside %s", field, *value, r))
+		}
+	}
+
+	check("temperature", &obs.Temperature, limits.Temperature)
+	check("feels like", obs.FeelsLike, limits.FeelsLike)
+	check("humidity", intToFloat(obs.Humidity), limits.Humidity)
+	check("pressure", obs.Pressure, limits.Pressure)
+	check("cloud cover", intToFloat(obs.CloudCover), limits.CloudCover)
+	check("visibility", obs.Visibility, limits.Visibility)
+	check("wind speed", &obs.WindSpeed, limits.WindSpeed)
+	check("wind gust", obs.WindGust, limits.WindGust)
+
+	if len(violations) > 0 {
+		return fmt.Errorf("%w: %s", ErrImplausibleObservation, strings.Join(violations, "; "))
+	}
+
+	return nil
+}
+
+// Check returns a description of each range whose minimum is above its maximum
+// once merged. Ranges are named by their path in the config e.g.
+// 'locations.sydney.temperature'.
+func (v *Validator) Check() []string {
+	var problems []string
+	check := func(prefix string, l Limits) {
+		for _, f := range l.fields() {
+			if f.r.Min != nil && f.r.Max != nil && *f.r.Min > *f.r.Max {
+				problems = append(problems, fmt.Sprintf("%s.%s %s has min above max", prefix, f.name, f.r))
+			}
+		}
+	}
+
+	check("limits", v.defaults)
+
+	names := make([]string, 0, len(v.locations))
+	for name := range v.locations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		check("locations."+name, v.locations[name])
+	}
+
+	return problems
+}
+
+// String formats the range in interval notation e.g. '[0, 100]'.
+func (r Range) String() string {
+	bound := func(b *float64, unbounded string) string {
+		if b == nil {
+			return unbounded
+		}
+		return fmt.Sprintf("%g", *b)
+	}
+	return fmt.Sprintf("[%s, %s]", bound(r.Min, "-inf"), bound(r.Max, "+inf"))
+}
+
+// merge returns the limits with any bounds set in override replaced.
+func (l Limits) merge(override Limits) Limits {
+	mergeRange := func(base Range, override Range) Range {
+		if override.Min != nil {
+			base.Min = override.Min
+		}
+		if override.Max != nil {
+			base.Max = override.Max
+		}
+		return base
+	}
+
+	return Limits{
+		Temperature: mergeRange(l.Temperature, override.Temperature),
+		FeelsLike:   mergeRange(l.FeelsLike, override.FeelsLike),
+		Humidity:    mergeRange(l.Humidity, override.Humidity),
+		Pressure:    mergeRange(l.Pressure, override.Pressure),
+		CloudCover:  mergeRange(l.CloudCover, override.CloudCover),
+		Visibility:  mergeRange(l.Visibility, override.Visibility),
+		WindSpeed:   mergeRange(l.WindSpeed, override.WindSpeed),
+		WindGust:    mergeRange(l.WindGust, override.WindGust),
+	}
+}
+
+// namedRange is a range of the limits named as in the config.
+type namedRange struct {
+	name string
+	r    Range
+}
+
+func (l Limits) fields() []namedRange {
+	return []namedRange{
+		{"temperature", l.Temperature},
+		{"feelsLike", l.FeelsLike},
+		{"humidity", l.Humidity},
+		{"pressure", l.Pressure},
+		{"cloudCover", l.CloudCover},
+		{"visibility", l.Visibility},
+		{"windSpeed", l.WindSpeed},
+		{"windGust", l.WindGust},
+	}
+}
+
+// isEmpty returns true if the observation has no values, as is the case when
+// decoding an empty or error body that was sent with a success status code.
+func (o *Observation) isEmpty() bool {
+	return o.Temperature == 0 && o.WindSpeed == 0 &&
+		o.FeelsLike == nil && o.Humidity == nil && o.Pressure == nil &&
+		o.CloudCover == nil && o.Visibility == nil && o.WindDegree == nil &&
+		o.WindGust == nil && (o.Condition == "" || o.Condition == ConditionUnknown)
+}
+
+func newRange(min float64, max float64) Range {
+	return Range{Min: &min, Max: &max}
+}
+
+func intToFloat(v *int) *float64 {
+	if v == nil {
+		return nil
+	}
+	f := float64(*v)
+	return &f
+}
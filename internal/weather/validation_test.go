@@ -0,0 +1,110 @@
+package weather
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidator_Validate(t *testing.T) {
+	sydney := Limits{
+		Temperature: Range{Min: ptr(-10.0), Max: ptr(50.0)},
+		WindSpeed:   Range{Max: ptr(200.0)},
+	}
+	v := NewValidator(Limits{}, map[string]Limits{"Sydney": sydney})
+
+	tests := []struct {
+		name     string
+		location string
+		obs      Observation
+		wantErr  string
+	}{
+		{
+			name:     "plausible",
+			location: "sydney",
+			obs:      Observation{Temperature: 21, WindSpeed: 15, Humidity: ptr(60), Condition: ConditionClear},
+		},
+		{
+			name:     "zero values with condition",
+			location: "sydney",
+			obs:      Observation{Condition: ConditionClear},
+		},
+		{
+			name:     "empty",
+			location: "sydney",
+			obs:      Observation{},
+			wantErr:  "implausible observation: no values reported",
+		},
+		{
+			name:     "default temperature limit",
+			location: "melbourne",
+			obs:      Observation{Temperature: 400, WindSpeed: 10},
+			wantErr:  "implausible observation: temperature 400 outside [-90, 60]",
+		},
+		{
+			name:     "location temperature limit",
+			location: "sydney",
+			obs:      Observation{Temperature: 55, WindSpeed: 10},
+			wantErr:  "implausible observation: temperature 55 outside [-10, 50]",
+		},
+		{
+			name:     "location falls back to default bound",
+			location: "sydney",
+			obs:      Observation{Temperature: 20, WindSpeed: -1},
+			wantErr:  "implausible observation: wind speed -1 outside [0, 200]",
+		},
+		{
+			name:     "multiple violations",
+			location: "sydney",
+			obs:      Observation{Temperature: 20, WindSpeed: 10, Humidity: ptr(120), Pressure: ptr(0.0)},
+			wantErr:  "implausible observation: humidity 120 outside [0, 100]; pressure 0 outside [850, 1090]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Validate(tt.location, &tt.obs)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, ErrImplausibleObservation)
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestNewValidator_defaults(t *testing.T) {
+	v := NewValidator(Limits{Temperature: Range{Max: ptr(45.0)}}, nil)
+
+	err := v.Validate("sydney", &Observation{Temperature: 46, WindSpeed: 10})
+	require.EqualError(t, err, "implausible observation: temperature 46 outside [-90, 45]")
+
+	// The package defaults are not modified
+	require.Equal(t, 60.0, *DefaultLimits.Temperature.Max)
+}
+
+func TestValidator_Check(t *testing.T) {
+	v := NewValidator(
+		Limits{Humidity: Range{Min: ptr(101.0)}},
+		map[string]Limits{
+			"Sydney":    {Temperature: Range{Min: ptr(70.0)}},
+			"melbourne": {},
+		},
+	)
+
+	require.Equal(t, []string{
+		"limits.humidity [101, 100] has min above max",
+		"locations.melbourne.humidity [101, 100] has min above max",
+		"locations.sydney.temperature [70, 60] has min above max",
+		"locations.sydney.humidity [101, 100] has min above max",
+	}, v.Check())
+
+	require.Empty(t, NewValidator(Limits{}, map[string]Limits{"sydney": {}}).Check())
+}
+
+func TestRange_String(t *testing.T) {
+	require.Equal(t, "[0, 100]", newRange(0, 100).String())
+	require.Equal(t, "[-inf, 1.5]", Range{Max: ptr(1.5)}.String())
+	require.Equal(t, "[-2, +inf]", Range{Min: ptr(-2.0)}.String())
+}
@@ -0,0 +1,82 @@
+package weather
+
+import "math"
+
+// SpeedUnit is a unit of speed used by providers.
+type SpeedUnit int
+
+const (
+	KilometresPerHour SpeedUnit = iota
+	MetresPerSecond
+	MilesPerHour
+	Knots
+)
+
+// kmhPerUnit is the number of kilometres per hour in one of each unit.
+var kmhPerUnit = map[SpeedUnit]float64{
+	KilometresPerHour: 1,
+	MetresPerSecond:   metresPerSecToKmh,
+	MilesPerHour:      1.609344,
+	Knots:             1.852,
+}
+
+// ToKmh converts a speed in the unit to kilometres per hour.
+func ToKmh(speed float64, unit SpeedUnit) float64 {
+	return speed * kmhPerUnit[unit]
+}
+
+// Beaufort is a force on the Beaufort wind scale.
+type Beaufort struct {
+	Force       int
+	Description string
+}
+
+// beaufortScale is ordered by force. Each upper bound is the exclusive wind
+// speed in metres per second at which the next force begins.
+var beaufortScale = []struct {
+	upperBound  float64
+	description string
+}{
+	{0.5, "Calm"},
+	{1.6, "Light air"},
+	{3.4, "Light breeze"},
+	{5.5, "Gentle breeze"},
+	{8.0, "Moderate breeze"},
+	{10.8, "Fresh breeze"},
+	{13.9, "Strong breeze"},
+	{17.2, "Near gale"},
+	{20.8, "Gale"},
+	{24.5, "Strong gale"},
+	{28.5, "Storm"},
+	{32.7, "Violent storm"},
+	{math.Inf(1), "Hurricane force"},
+}
+
+// BeaufortForce returns the Beaufort force for a wind speed in the unit.
+func BeaufortForce(speed float64, unit SpeedUnit) Beaufort {
+	ms := ToKmh(speed, unit) / metresPerSecToKmh
+	for force, level := range beaufortScale {
+		if ms < level.upperBound {
+			return Beaufort{Force: force, Description: level.description}
+		}
+	}
+	// Unreachable since the last upper bound is infinite
+	return Beaufort{}
+}
+
+// compassPoints are the 16 compass points clockwise from north.
+var compassPoints = []string{
+	"N", "NNE", "NE", "ENE", "E", "ESE", "SE", "SSE",
+	"S", "SSW", "SW", "WSW", "W", "WNW", "NW", "NNW",
+}
+
+// CompassDirection returns the cardinal, intercardinal or secondary
+// intercardinal direction for a bearing in degrees, e.g. 20 is 'NNE'.
+func CompassDirection(degrees float64) string {
+	degrees = math.Mod(degrees, 360)
+	if degrees < 0 {
+		degrees += 360
+	}
+	sector := int(math.Floor(degrees/22.5+0.5)) % len(compassPoints)
+	return compassPoints[sector]
+}
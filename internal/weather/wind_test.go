@@ -0,0 +1,61 @@
+package weather
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestToKmh(t *testing.T) {
+	require.Equal(t, 10.0, ToKmh(10, KilometresPerHour))
+	require.InDelta(t, 36, ToKmh(10, MetresPerSecond), 0.0001)
+	require.InDelta(t, 16.09344, ToKmh(10, MilesPerHour), 0.0001)
+	require.InDelta(t, 18.52, ToKmh(10, Knots), 0.0001)
+}
+
+func TestBeaufortForce(t *testing.T) {
+	tests := []struct {
+		name      string
+		speed     float64
+		unit      SpeedUnit
+		wantForce int
+		wantDesc  string
+	}{
+		{name: "calm", speed: 0, unit: KilometresPerHour, wantForce: 0, wantDesc: "Calm"},
+		{name: "gentle breeze kmh", speed: 15, unit: KilometresPerHour, wantForce: 3, wantDesc: "Gentle breeze"},
+		{name: "gentle breeze ms", speed: 15 / 3.6, unit: MetresPerSecond, wantForce: 3, wantDesc: "Gentle breeze"},
+		{name: "gale knots", speed: 37, unit: Knots, wantForce: 8, wantDesc: "Gale"},
+		{name: "strong breeze mph", speed: 27, unit: MilesPerHour, wantForce: 6, wantDesc: "Strong breeze"},
+		{name: "lower bound inclusive", speed: 5.5, unit: MetresPerSecond, wantForce: 4, wantDesc: "Moderate breeze"},
+		{name: "hurricane", speed: 200, unit: KilometresPerHour, wantForce: 12, wantDesc: "Hurricane force"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := BeaufortForce(tt.speed, tt.unit)
+			require.Equal(t, tt.wantForce, got.Force)
+			require.Equal(t, tt.wantDesc, got.Description)
+		})
+	}
+}
+
+func TestCompassDirection(t *testing.T) {
+	tests := []struct {
+		degrees float64
+		want    string
+	}{
+		{degrees: 0, want: "N"},
+		{degrees: 11, want: "N"},
+		{degrees: 12, want: "NNE"},
+		{degrees: 45, want: "NE"},
+		{degrees: 90, want: "E"},
+		{degrees: 200, want: "SSW"},
+		{degrees: 350, want: "N"},
+		{degrees: 360, want: "N"},
+		{degrees: -90, want: "W"},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.want, CompassDirection(tt.degrees), "%v degrees", tt.degrees)
+	}
+}
@@ -1,46 +1,282 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
 	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+	_ "time/tzdata" // Provider local times are interpreted in the city's time zone
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 
 	"github.com/joshjon/sydneyweather/internal/api/v1"
+	"github.com/joshjon/sydneyweather/internal/auth"
 	"github.com/joshjon/sydneyweather/internal/config"
+	"github.com/joshjon/sydneyweather/internal/logging"
+	"github.com/joshjon/sydneyweather/internal/tracing"
 )
 
-// main starts a new echo server registered with the sydney weather service.
+// Shutdown steps after requests are drained have their own timeouts so that a
+// slow drain does not leave them no time. Together with the shutdown timeout
+// they must fit in the orchestrator's grace period, e.g. 10s for docker stop.
+const (
+	// backgroundStopTimeout is how long outlier sampling and config watching
+	// are given to stop.
+	backgroundStopTimeout = time.Second
+	// traceFlushTimeout is how long pending spans are given to be exported.
+	traceFlushTimeout = 2 * time.Second
+)
+
+// main starts a new echo server registered with the sydney weather service and
+// runs it until SIGINT or SIGTERM is received. With -print-config the effective
+// config is printed instead.
 func main() {
+	// The level is changed once the config is loaded and whenever it is reloaded
+	var level slog.LevelVar
+	logger := logging.New(os.Stderr, &level)
+
+	printConfig := flag.Bool("print-config", false, "print the effective config with secrets masked and exit")
+
 	cfg, err := config.Load()
+	var invalid *config.ValidationError
+	if errors.As(err, &invalid) {
+		// An invalid config is still printed so that the problems can be found in it
+		if *printConfig {
+			printEffectiveConfig(logger, invalid.Config)
+		}
+		logger.Error("invalid config", "problems", invalid.Problems)
+		os.Exit(1)
+	}
 	if err != nil {
-		log.Fatalf("error loading config: %v\n", err)
+		fatal(logger, "error loading config", err)
 	}
 
-	e := echo.New()
-	e.Use(middleware.Logger())
+	if *printConfig {
+		printEffectiveConfig(logger, cfg)
+		return
+	}
 
-	serviceCfg := api.Config{
-		WeatherStackAPIKey: cfg.WeatherStackAPIKey,
-		OpenWeatherAPIKey:  cfg.OpenWeatherAPIKey,
-		CacheExpiry:        cfg.CacheExpiry,
+	setLevel(&level, cfg)
+	slog.SetDefault(logger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Signals are handled as usual once shutdown starts, so a second one exits
+	// immediately
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	if err = run(ctx, logger, &level, cfg); err != nil {
+		fatal(logger, "error running server", err)
 	}
+}
 
-	service := api.NewService(serviceCfg)
-	registerService(e, service)
+// run serves requests until the context is done and then shuts down
+// gracefully: the listener is closed, in-flight requests are drained within
+// the shutdown timeout, then background sampling and config watching are
+// stopped and pending spans are flushed, each within its own timeout. Metrics are scraped
+// and caches are in memory, so neither needs flushing.
+func run(ctx context.Context, logger *slog.Logger, level *slog.LevelVar, cfg *config.Config) error {
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		ServiceName: "sydneyweather",
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		return fmt.Errorf("error setting up tracing: %w", err)
+	}
+
+	service := api.NewService(serviceConfig(cfg))
+	e := newServer(logger, service)
+
+	authenticator := auth.NewAuthenticator(authConfig(cfg))
+
+	// Connections are kept open on reload since only the service's settings
+	// change
+	watcher := config.NewWatcher(cfg, logger, func(cfg *config.Config) {
+		setLevel(level, cfg)
+		service.Reload(serviceConfig(cfg))
+		authenticator.Update(authConfig(cfg))
+	})
+	registerService(e, service, watcher, authenticator)
+
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	var background sync.WaitGroup
+	background.Add(2)
+	go func() {
+		defer background.Done()
+		service.Monitor(backgroundCtx)
+	}()
+	go func() {
+		defer background.Done()
+		watcher.Run(backgroundCtx)
+	}()
+	backgroundDone := make(chan struct{})
+	go func() {
+		background.Wait()
+		close(backgroundDone)
+	}()
 
 	addr := &net.TCPAddr{
 		IP:   []byte{0, 0, 0, 0},
 		Port: cfg.ServerPort,
 	}
 
-	if err = e.Start(addr.String()); err != nil {
-		log.Fatalf("error starting server: %v\n", err)
+	serveErr := make(chan error, 1)
+	go func() {
+		logger.Info("starting server", "addr", addr.String())
+		serveErr <- e.Start(addr.String())
+	}()
+
+	select {
+	case err = <-serveErr:
+		err = fmt.Errorf("error starting server: %w", err)
+	case <-ctx.Done():
+		logger.Info("shutting down", "timeout", cfg.ShutdownTimeout.String())
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if shutdownErr := e.Shutdown(shutdownCtx); shutdownErr != nil {
+		logger.Error("error draining requests", logging.KeyError, shutdownErr)
+	}
+
+	stopBackground()
+	select {
+	case <-backgroundDone:
+	case <-time.After(backgroundStopTimeout):
+		logger.Error("error stopping outlier monitor and config watcher", logging.KeyError, context.DeadlineExceeded)
+	}
+
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), traceFlushTimeout)
+	defer cancelFlush()
+
+	if shutdownErr := shutdownTracing(flushCtx); shutdownErr != nil {
+		logger.Error("error shutting down tracing", logging.KeyError, shutdownErr)
+	}
+
+	if err == nil {
+		logger.Info("server stopped")
 	}
+	return err
 }
 
-func registerService(e *echo.Echo, s *api.Service) {
-	v1 := e.Group("/v1")
+// newServer creates an echo server with the middleware every request passes
+// through. Request logging wraps tracing and metrics so that it sees the errors
+// they record.
+func newServer(logger *slog.Logger, service *api.Service) *echo.Echo {
+	e := echo.New()
+	e.HideBanner, e.HidePort = true, true
+	e.HTTPErrorHandler = api.HTTPErrorHandler
+	e.Use(middleware.RequestID())
+	e.Use(logging.Middleware(logger))
+	e.Use(tracing.Middleware())
+	e.Use(service.MetricsMiddleware())
+	return e
+}
+
+// serviceConfig returns the service settings from the config.
+func serviceConfig(cfg *config.Config) api.Config {
+	return api.Config{
+		WeatherStackAPIKey:  cfg.WeatherStackAPIKey,
+		OpenWeatherAPIKey:   cfg.OpenWeatherAPIKey,
+		CacheExpiry:         cfg.CacheExpiry,
+		ForecastCacheExpiry: cfg.ForecastCacheExpiry,
+		ProviderOrder:       cfg.Providers,
+		ProviderTimeout:     cfg.ProviderTimeout,
+		Limits:              cfg.Validation.Limits,
+		LocationLimits:      cfg.Validation.Locations,
+		Outlier: api.OutlierConfig{
+			Interval:             cfg.Outlier.Interval,
+			Window:               cfg.Outlier.Window,
+			TemperatureThreshold: cfg.Outlier.TemperatureThreshold,
+			WindSpeedThreshold:   cfg.Outlier.WindSpeedThreshold,
+		},
+		Health: api.HealthConfig{
+			FailureThreshold: cfg.Health.FailureThreshold,
+			Cooldown:         cfg.Health.Cooldown,
+			ReadyWhileStale:  cfg.Health.ReadyWhileStale,
+			MaxStaleAge:      cfg.Health.MaxStaleAge,
+		},
+	}
+}
+
+// authConfig returns the client authentication settings from the config.
+func authConfig(cfg *config.Config) auth.Config {
+	clients := make([]auth.Client, 0, len(cfg.Auth.Clients))
+	for _, c := range cfg.Auth.Clients {
+		clients = append(clients, auth.Client{
+			Name:       c.Name,
+			Key:        c.Key,
+			RateLimit:  c.RateLimit,
+			Burst:      c.Burst,
+			DailyQuota: c.DailyQuota,
+			Admin:      c.Admin,
+		})
+	}
+
+	return auth.Config{
+		Enabled: cfg.Auth.Enabled,
+		Header:  cfg.Auth.Header,
+		Clients: clients,
+	}
+}
+
+// setLevel sets the log level from the config, which has been validated.
+func setLevel(level *slog.LevelVar, cfg *config.Config) {
+	if l, err := logging.ParseLevel(cfg.LogLevel); err == nil {
+		level.Set(l)
+	}
+}
+
+// printEffectiveConfig prints the config to stdout with secrets masked.
+func printEffectiveConfig(logger *slog.Logger, cfg *config.Config) {
+	if err := cfg.Print(os.Stdout); err != nil {
+		fatal(logger, "error printing config", err)
+	}
+}
+
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, logging.KeyError, err)
+	os.Exit(1)
+}
+
+// registerService registers the service's routes. Metrics and health checks are
+// left open for scrapers and orchestrators, while API routes need a client API
+// key when auth is enabled.
+func registerService(e *echo.Echo, s *api.Service, w *config.Watcher, a *auth.Authenticator) {
+	e.GET("/metrics", echo.WrapHandler(s.MetricsHandler()))
+	e.GET("/healthz", s.GetHealth)
+	e.GET("/readyz", s.GetReadiness)
+
+	admin := e.Group("/admin", a.Middleware(), a.RequireAdmin())
+	admin.GET("/usage", a.GetUsage)
+	admin.GET("/diagnostics/providers", s.GetProviderDiagnostics)
+	admin.GET("/config/reloads", w.GetReloads)
+
+	v1 := e.Group("/v1", a.Middleware())
 	v1.GET("/weather", s.GetWeather)
+	v1.GET("/status", s.GetStatus)
+
+	v2 := e.Group("/v2", a.Middleware())
+	v2.GET("/weather", s.GetExtendedWeather)
+	v2.GET("/weather/forecast", s.GetForecast)
+	v2.GET("/weather/history", s.GetHistory)
+	v2.GET("/weather/alerts", s.GetAlerts)
+	v2.GET("/air-quality", s.GetAirQuality)
+	v2.GET("/astronomy", s.GetAstronomy)
 }
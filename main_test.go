@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshjon/sydneyweather/internal/api/v1"
+	"github.com/joshjon/sydneyweather/internal/auth"
+	"github.com/joshjon/sydneyweather/internal/logging"
+)
+
+func TestNewServer_logsRequestErrors(t *testing.T) {
+	var buf bytes.Buffer
+	e := newServer(logging.New(&buf, slog.LevelInfo), api.NewService(api.Config{}))
+	e.GET("/some-route", func(ctx echo.Context) error {
+		return echo.NewHTTPError(http.StatusServiceUnavailable).SetInternal(errors.New("some-error"))
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/some-route", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	require.Equal(t, "request", line["msg"])
+	require.Equal(t, float64(http.StatusServiceUnavailable), line["status"])
+	require.Equal(t, "code=503, message=Service Unavailable, internal=some-error", line[logging.KeyError])
+}
+
+func TestNewServer_logsClient(t *testing.T) {
+	var buf bytes.Buffer
+	e := newServer(logging.New(&buf, slog.LevelInfo), api.NewService(api.Config{}))
+	a := auth.NewAuthenticator(auth.Config{Enabled: true, Clients: []auth.Client{{Name: "some-client", Key: "some-key"}}})
+	e.GET("/some-route", func(ctx echo.Context) error {
+		return ctx.NoContent(http.StatusOK)
+	}, a.Middleware())
+
+	req := httptest.NewRequest(http.MethodGet, "/some-route", nil)
+	req.Header.Set(auth.DefaultHeader, "some-key")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	require.Equal(t, "request", line["msg"])
+	require.Equal(t, "some-client", line[logging.KeyClient])
+}